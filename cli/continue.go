@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"github.com/dominikbraun/timetrace/core"
+	"github.com/dominikbraun/timetrace/out"
+
+	"github.com/spf13/cobra"
+)
+
+func continueCommand(t *core.Timetrace) *cobra.Command {
+	continueCmd := &cobra.Command{
+		Use:   "continue [<KEY>|latest|@ID]",
+		Short: "Start a new record with the project and tags of the latest or a given record",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var record *core.Record
+			var err error
+
+			if len(args) > 0 {
+				recordTime, err := getRecordTimeFromArg(t, args[0])
+				if err != nil {
+					out.Err("%s", err.Error())
+					return
+				}
+
+				if record, err = t.LoadRecord(recordTime); err != nil {
+					out.Err("failed to load record: %s", err.Error())
+					return
+				}
+			} else if record, err = t.LoadLatestRecord(); err != nil {
+				out.Err("failed to load latest record: %s", err.Error())
+				return
+			}
+
+			if record == nil {
+				out.Err("there is no record to continue")
+				return
+			}
+
+			if err := t.Continue(*record); err != nil {
+				out.Err("failed to continue record: %s", err.Error())
+				return
+			}
+
+			project := defaultString
+			if record.Project != nil {
+				project = record.Project.Key
+			}
+
+			out.Success("Continued tracking time for %s", project)
+		},
+	}
+
+	return continueCmd
+}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"strings"
 	"time"
 
 	"github.com/dominikbraun/timetrace/core"
@@ -20,11 +21,19 @@ func createCommand(t *core.Timetrace) *cobra.Command {
 
 	create.AddCommand(createProjectCommand(t))
 	create.AddCommand(createRecordCommand(t))
+	create.AddCommand(createTagCommand(t))
 
 	return create
 }
 
+type createProjectOptions struct {
+	rate     float64
+	currency string
+}
+
 func createProjectCommand(t *core.Timetrace) *cobra.Command {
+	var options createProjectOptions
+
 	createProject := &cobra.Command{
 		Use:   "project <KEY>",
 		Short: "Create a new project",
@@ -32,8 +41,15 @@ func createProjectCommand(t *core.Timetrace) *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			key := args[0]
 
+			if options.rate < 0 {
+				out.Err("the hourly rate must not be negative")
+				return
+			}
+
 			project := core.Project{
-				Key: key,
+				Key:      key,
+				Rate:     options.rate,
+				Currency: options.currency,
 			}
 
 			if err := t.SaveProject(project, false); err != nil {
@@ -45,21 +61,68 @@ func createProjectCommand(t *core.Timetrace) *cobra.Command {
 		},
 	}
 
+	createProject.Flags().Float64Var(&options.rate, "rate",
+		0, "Hourly rate billed for the project")
+
+	createProject.Flags().StringVar(&options.currency, "currency",
+		"", "Currency of the hourly rate, e.g. EUR")
+
 	return createProject
 }
 
+type createTagOptions struct {
+	description string
+	color       string
+}
+
+func createTagCommand(t *core.Timetrace) *cobra.Command {
+	var options createTagOptions
+
+	createTag := &cobra.Command{
+		Use:   "tag <KEY>",
+		Short: "Register a new tag",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			// Accept tags written like in the start command as well.
+			key := strings.TrimPrefix(args[0], TagsPrefix)
+
+			tag := core.Tag{
+				Key:         key,
+				Description: options.description,
+				Color:       options.color,
+			}
+
+			if err := t.SaveTag(tag, false); err != nil {
+				out.Err("failed to create tag: %s", err.Error())
+				return
+			}
+
+			out.Success("Created tag %s", key)
+		},
+	}
+
+	createTag.Flags().StringVarP(&options.description, "description", "d",
+		"", "Description of the tag")
+
+	createTag.Flags().StringVar(&options.color, "color",
+		"", "Color of the tag, e.g. red or #ff0000")
+
+	return createTag
+}
+
 func createRecordCommand(t *core.Timetrace) *cobra.Command {
 	var options startOptions
 	var usage string
 	if t.Config().Use12Hours {
-		usage = "record <PROJECT KEY> {<YYYY-MM-DD>|today|yesterday} <HH:MMPM> <HH:MMPM>"
+		usage = "record <PROJECT KEY> {<YYYY-MM-DD>|today|yesterday|monday|last monday|3d ago} <HH:MMPM> <HH:MMPM>"
 	} else {
-		usage = "record <PROJECT KEY> {<YYYY-MM-DD>|today|yesterday} <HH:MM> <HH:MM>"
+		usage = "record <PROJECT KEY> {<YYYY-MM-DD>|today|yesterday|monday|last monday|3d ago} <HH:MM> <HH:MM>"
 	}
 	createRecord := &cobra.Command{
-		Use:   usage,
-		Short: "Create a new record",
-		Args:  cobra.ExactArgs(4),
+		Use:     usage,
+		Short:   "Create a new record",
+		Example: "  timetrace create record web-shop yesterday 09:00 12:00\n  timetrace create record web-shop -- -3d 09:00 12:00",
+		Args:    cobra.ExactArgs(4),
 		Run: func(cmd *cobra.Command, args []string) {
 			key := args[0]
 			project, err := t.LoadProject(key)
@@ -104,6 +167,8 @@ func createRecordCommand(t *core.Timetrace) *cobra.Command {
 				Start:      start,
 				End:        &end,
 				IsBillable: options.isBillable,
+				IsParallel: options.isParallel,
+				Note:       options.note,
 			}
 
 			collides, err := t.RecordCollides(record)
@@ -127,5 +192,11 @@ func createRecordCommand(t *core.Timetrace) *cobra.Command {
 	createRecord.Flags().BoolVarP(&options.isBillable, "billable", "b",
 		false, `mark tracked time as billable`)
 
+	createRecord.Flags().BoolVar(&options.isParallel, "parallel",
+		false, `allow the record to overlap with other records`)
+
+	createRecord.Flags().StringVarP(&options.note, "note", "m",
+		"", `add a note describing the work done`)
+
 	return createRecord
 }
@@ -17,11 +17,13 @@ var confirmed bool
 type deleteOptions struct {
 	Revert         bool
 	ExcludeRecords bool
+	Force          bool
 }
 
 const (
 	deleteProjectConfirmation = "Deleting project...Please confirm [y/N]: "
 	deleteRecordConfirmation  = "Deleting record...Please confirm [y/N]: "
+	deleteTagConfirmation     = "Deleting tag...Please confirm [y/N]: "
 	deleteRecordsWarning      = "Do you wish to delete project records? Please confirm [y/N]: "
 	revertRecordsWarning      = `Do you wish to restore project records from backups?
 Warning! This will overwrite any changes made after the most recent backup. Please confirm [y/N]: `
@@ -38,6 +40,7 @@ func deleteCommand(t *core.Timetrace) *cobra.Command {
 
 	delete.AddCommand(deleteProjectCommand(t))
 	delete.AddCommand(deleteRecordCommand(t))
+	delete.AddCommand(deleteTagCommand(t))
 	delete.PersistentFlags().BoolVar(&confirmed, "yes", false, "Do not ask for confirmation")
 
 	return delete
@@ -55,7 +58,7 @@ func deleteProjectCommand(t *core.Timetrace) *cobra.Command {
 			if options.Revert {
 				if !options.ExcludeRecords && askForConfirmation(revertRecordsWarning) {
 					defer func() {
-						if err := t.RevertRecordsByProject(key); err != nil {
+						if err := t.RevertRecordsByProject(key, options.Force); err != nil {
 							out.Err("failed to revert project records from backup: %s", err.Error())
 							return
 						}
@@ -84,26 +87,27 @@ func deleteProjectCommand(t *core.Timetrace) *cobra.Command {
 				return
 			}
 
-			defer func() {
-				if err := t.DeleteProject(project); err != nil {
-					out.Err("failed to delete %s", err.Error())
-					return
-				}
-			}()
-
+			// Delete the records first, so that the project is kept if any of
+			// its records can't be deleted, e.g. because they are locked.
 			if !options.ExcludeRecords && askForConfirmation(deleteRecordsWarning) {
-				// find and delete records.
-				if err := t.DeleteRecordsByProject(key); err != nil {
+				if err := t.DeleteRecordsByProject(key, options.Force); err != nil {
 					out.Err("failed to delete project records - %v", err)
+					return
 				}
 			}
 
+			if err := t.DeleteProject(project); err != nil {
+				out.Err("failed to delete %s", err.Error())
+				return
+			}
+
 			out.Success("Deleted project %s", key)
 		},
 	}
 
 	deleteProject.PersistentFlags().BoolVarP(&options.Revert, "revert", "r", false, "Restores the project to its state prior to the last 'delete' command.")
 	deleteProject.PersistentFlags().BoolVarP(&options.ExcludeRecords, "exclude-records", "e", false, "Exclude project records when deleting the project.")
+	deleteProject.PersistentFlags().BoolVar(&options.Force, "force", false, "Delete or revert project records even if they are locked.")
 
 	return deleteProject
 }
@@ -111,22 +115,23 @@ func deleteProjectCommand(t *core.Timetrace) *cobra.Command {
 func deleteRecordCommand(t *core.Timetrace) *cobra.Command {
 	var options deleteOptions
 	// Depending on the use12hours setting, the command syntax either is
-	// `record YYYY-MM-DD-HH-MM` or `record YYYY-MM-DD-HH-MMPM`.
-	use := fmt.Sprintf("record %s", t.Formatter().RecordKeyLayout())
+	// `record YYYY-MM-DD-HH-MM` or `record YYYY-MM-DD-HH-MMPM`. Records can be
+	// referenced by their ID as well.
+	use := fmt.Sprintf("record {%s|@ID}", t.Formatter().RecordKeyLayout())
 
 	deleteRecord := &cobra.Command{
 		Use:   use,
 		Short: "Delete a record",
 		Args:  cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			start, err := t.Formatter().ParseRecordKey(args[0])
+			start, err := getRecordTimeFromArg(t, args[0])
 			if err != nil {
-				out.Err("Failed to parse date argument: %s", err.Error())
+				out.Err("%s", err.Error())
 				return
 			}
 
 			if options.Revert {
-				if err := t.RevertRecord(start); err != nil {
+				if err := t.RevertRecord(start, options.Force); err != nil {
 					out.Err("failed to revert record: %s", err.Error())
 					return
 				}
@@ -142,6 +147,11 @@ func deleteRecordCommand(t *core.Timetrace) *cobra.Command {
 
 			showRecord(record, t.Formatter())
 
+			if record.IsLocked() && !options.Force {
+				out.Err("failed to delete record: %s", core.ErrRecordLocked.Error())
+				return
+			}
+
 			if !confirmed && !askForConfirmation(deleteRecordConfirmation) {
 				out.Info("Record NOT deleted")
 				return
@@ -152,7 +162,7 @@ func deleteRecordCommand(t *core.Timetrace) *cobra.Command {
 				return
 			}
 
-			if err := t.DeleteRecord(*record); err != nil {
+			if err := t.DeleteRecord(*record, options.Force); err != nil {
 				out.Err("failed to delete %s", err.Error())
 				return
 			}
@@ -162,10 +172,36 @@ func deleteRecordCommand(t *core.Timetrace) *cobra.Command {
 	}
 
 	deleteRecord.PersistentFlags().BoolVarP(&options.Revert, "revert", "r", false, "Restores the record to its state prior to the last 'delete' command.")
+	deleteRecord.PersistentFlags().BoolVar(&options.Force, "force", false, "Delete or revert the record even if it is locked.")
 
 	return deleteRecord
 }
 
+func deleteTagCommand(t *core.Timetrace) *cobra.Command {
+	deleteTag := &cobra.Command{
+		Use:   "tag <KEY>",
+		Short: "Delete a registered tag",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			key := strings.TrimPrefix(args[0], TagsPrefix)
+
+			if !confirmed && !askForConfirmation(deleteTagConfirmation) {
+				out.Info("Tag NOT deleted")
+				return
+			}
+
+			if err := t.DeleteTag(key); err != nil {
+				out.Err("failed to delete tag: %s", err.Error())
+				return
+			}
+
+			out.Success("Deleted tag %s", key)
+		},
+	}
+
+	return deleteTag
+}
+
 func askForConfirmation(msg string) bool {
 	reader := bufio.NewReader(os.Stdin)
 	fmt.Fprint(os.Stderr, msg)
@@ -2,7 +2,6 @@ package cli
 
 import (
 	"errors"
-	"strconv"
 	"strings"
 	"time"
 
@@ -65,9 +64,18 @@ func editProjectCommand(t *core.Timetrace) *cobra.Command {
 }
 
 type editOptions struct {
-	Plus   string
-	Minus  string
-	Revert bool
+	Plus        string
+	Minus       string
+	Note        string
+	Start       string
+	End         string
+	Project     string
+	AddTags     []string
+	RemoveTags  []string
+	Billable    bool
+	NonBillable bool
+	Revert      bool
+	Force       bool
 }
 
 func editRecordCommand(t *core.Timetrace) *cobra.Command {
@@ -83,6 +91,11 @@ func editRecordCommand(t *core.Timetrace) *cobra.Command {
 				return
 			}
 
+			if options.Billable && options.NonBillable {
+				out.Err("billable and non-billable flag can not be combined: %s", errors.New("edit not possible"))
+				return
+			}
+
 			recordTime, err := getRecordTimeFromArg(t, args[0])
 
 			if err != nil {
@@ -91,7 +104,7 @@ func editRecordCommand(t *core.Timetrace) *cobra.Command {
 			}
 
 			if options.Revert {
-				if err := t.RevertRecord(recordTime); err != nil {
+				if err := t.RevertRecord(recordTime, options.Force); err != nil {
 					out.Err("failed to revert record: %s", err.Error())
 				} else {
 					out.Info("Record backup restored successfully")
@@ -99,22 +112,74 @@ func editRecordCommand(t *core.Timetrace) *cobra.Command {
 				return
 			}
 
+			// Check the lock before backing up the record, since the
+			// backup of a locked record shouldn't be overwritten.
+			record, err := t.LoadRecord(recordTime)
+			if err != nil {
+				out.Err("failed to read record: %s", err.Error())
+				return
+			}
+
+			if record.IsLocked() && !options.Force {
+				out.Err("failed to edit record: %s", core.ErrRecordLocked.Error())
+				return
+			}
+
 			if err := t.BackupRecord(recordTime); err != nil {
 				out.Err("failed to backup record before edit: %s", err.Error())
 				return
 			}
 
-			if options.Minus == "" && options.Plus == "" {
+			if cmd.Flags().NFlag() == 0 || (cmd.Flags().NFlag() == 1 && options.Force) {
 				out.Info("Opening %s in default editor", recordTime)
-				if err := t.EditRecordManual(recordTime); err != nil {
+				if err := t.EditRecordManual(recordTime, options.Force); err != nil {
 					out.Err("failed to edit record: %s", err.Error())
 					return
 				}
-			} else {
-				if err := t.EditRecord(recordTime, options.Plus, options.Minus); err != nil {
-					out.Err("failed to edit record: %s", err.Error())
+				out.Success("successfully edited %s", recordTime)
+				return
+			}
+
+			changes := core.RecordChanges{
+				Plus:       options.Plus,
+				Minus:      options.Minus,
+				AddTags:    trimTagsPrefix(options.AddTags),
+				RemoveTags: trimTagsPrefix(options.RemoveTags),
+			}
+
+			if cmd.Flags().Changed("note") {
+				changes.Note = &options.Note
+			}
+
+			if cmd.Flags().Changed("project") {
+				changes.ProjectKey = &options.Project
+			}
+
+			if options.Billable || options.NonBillable {
+				changes.IsBillable = &options.Billable
+			}
+
+			if options.Start != "" {
+				start, err := parseRecordTime(t, options.Start, record.Start)
+				if err != nil {
+					out.Err("failed to parse start time: %s", err.Error())
 					return
 				}
+				changes.Start = &start
+			}
+
+			if options.End != "" {
+				end, err := parseRecordTime(t, options.End, record.Start)
+				if err != nil {
+					out.Err("failed to parse end time: %s", err.Error())
+					return
+				}
+				changes.End = &end
+			}
+
+			if err := t.EditRecord(recordTime, changes, options.Force); err != nil {
+				out.Err("failed to edit record: %s", err.Error())
+				return
 			}
 
 			out.Success("successfully edited %s", recordTime)
@@ -123,11 +188,47 @@ func editRecordCommand(t *core.Timetrace) *cobra.Command {
 
 	editRecord.PersistentFlags().StringVarP(&options.Plus, "plus", "p", "", "Adds the given duration to the end time of the record")
 	editRecord.PersistentFlags().StringVarP(&options.Minus, "minus", "m", "", "Substracts the given duration to the end time of the record")
+	editRecord.PersistentFlags().StringVar(&options.Note, "note", "", "Replaces the note of the record")
+	editRecord.PersistentFlags().StringVar(&options.Start, "start", "", "Sets the start time of the record as <HH:MM> on the record's day, as record key or as date and time like 'yesterday 17:30'")
+	editRecord.PersistentFlags().StringVar(&options.End, "end", "", "Sets the end time of the record as <HH:MM> on the record's day, as record key or as date and time like 'yesterday 17:30'")
+	editRecord.PersistentFlags().StringVar(&options.Project, "project", "", "Moves the record to the given project")
+	editRecord.PersistentFlags().StringSliceVar(&options.AddTags, "add-tag", nil, "Adds the given tags to the record")
+	editRecord.PersistentFlags().StringSliceVar(&options.RemoveTags, "remove-tag", nil, "Removes the given tags from the record")
+	editRecord.PersistentFlags().BoolVar(&options.Billable, "billable", false, "Marks the record as billable")
+	editRecord.PersistentFlags().BoolVar(&options.NonBillable, "non-billable", false, "Marks the record as non-billable")
 	editRecord.PersistentFlags().BoolVarP(&options.Revert, "revert", "r", false, "Restores the record to it's state prior to the last 'edit' command.")
+	editRecord.PersistentFlags().BoolVar(&options.Force, "force", false, "Edit or revert the record even if it is locked.")
 
 	return editRecord
 }
 
+// parseRecordTime parses a time given to the start or end flag. The input is
+// either a full record key, a time on the same day as the given record start or
+// any date and time accepted by the --at flag of start and stop.
+func parseRecordTime(t *core.Timetrace, input string, recordStart time.Time) (time.Time, error) {
+	if key, err := t.Formatter().ParseRecordKey(input); err == nil {
+		return key, nil
+	}
+
+	if clock, err := t.Formatter().ParseTime(input); err == nil {
+		return t.Formatter().CombineDateAndTime(recordStart, clock), nil
+	}
+
+	return t.Formatter().ParseDateTime(input)
+}
+
+// trimTagsPrefix strips the optional tag prefix from the given tags.
+func trimTagsPrefix(tags []string) []string {
+	trimmed := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		trimmed = append(trimmed, strings.TrimPrefix(tag, TagsPrefix))
+	}
+	return trimmed
+}
+
+// getRecordTimeFromArg returns the start time of the record referenced by the
+// given argument, which is either a record key, `latest` or a record ID as
+// printed by `list records`.
 func getRecordTimeFromArg(t *core.Timetrace, arg string) (time.Time, error) {
 	var recordTime time.Time
 	var err error
@@ -138,20 +239,14 @@ func getRecordTimeFromArg(t *core.Timetrace, arg string) (time.Time, error) {
 			err = errors.New("error on loading last record: " + err.Error())
 			return recordTime, err
 		}
+		if rec == nil {
+			return recordTime, errors.New("no records found")
+		}
 		recordTime = rec.Start
 	} else if strings.Contains(arg, "@") {
-		id, err := strconv.Atoi(arg[1:])
+		rec, err := t.LoadRecordByID(arg)
 		if err != nil {
-			err = errors.New("error on parsing ID: " + err.Error())
-			return recordTime, err
-		}
-		rec, err := t.LoadRecordByID(id)
-		if err != nil {
-			err = errors.New("error on loading last record: " + err.Error())
-			return recordTime, err
-		}
-		if rec == nil {
-			err = errors.New("no record of given ID started today")
+			err = errors.New("error on loading record by ID: " + err.Error())
 			return recordTime, err
 		}
 		recordTime = rec.Start
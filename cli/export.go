@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/dominikbraun/timetrace/config"
+	"github.com/dominikbraun/timetrace/core"
+	"github.com/dominikbraun/timetrace/out"
+
+	"github.com/spf13/cobra"
+)
+
+func exportCommand(t *core.Timetrace) *cobra.Command {
+	export := &cobra.Command{
+		Use:   "export <FILE>",
+		Short: "Export all projects, records and the config into an archive",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			// The config file is optional, so it is only exported if it exists.
+			configFile, err := ioutil.ReadFile(config.FilePath())
+			if err != nil && !os.IsNotExist(err) {
+				out.Err("failed to read config: %s", err.Error())
+				return
+			}
+
+			file, err := os.Create(args[0])
+			if err != nil {
+				out.Err("failed to create archive: %s", err.Error())
+				return
+			}
+			defer file.Close()
+
+			manifest, err := t.Export(file, configFile)
+			if err != nil {
+				out.Err("failed to export: %s", err.Error())
+				return
+			}
+
+			if err := file.Close(); err != nil {
+				out.Err("failed to write archive: %s", err.Error())
+				return
+			}
+
+			out.Success("Exported %d projects, %d records and %d tags to %s", manifest.Projects, manifest.Records, manifest.Tags, args[0])
+		},
+	}
+
+	return export
+}
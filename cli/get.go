@@ -48,17 +48,18 @@ func getProjectCommand(t *core.Timetrace) *cobra.Command {
 func getRecordCommand(t *core.Timetrace) *cobra.Command {
 
 	// Depending on the use12hours setting, the command syntax either is
-	// `record YYYY-MM-DD-HH-MM` or `record YYYY-MM-DD-HH-MMPM`.
-	use := fmt.Sprintf("record %s", t.Formatter().RecordKeyLayout())
+	// `record YYYY-MM-DD-HH-MM` or `record YYYY-MM-DD-HH-MMPM`. Records can be
+	// referenced by their ID as well.
+	use := fmt.Sprintf("record {%s|@ID}", t.Formatter().RecordKeyLayout())
 
 	getRecord := &cobra.Command{
 		Use:   use,
 		Short: "Display a record",
 		Args:  cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			start, err := t.Formatter().ParseRecordKey(args[0])
+			start, err := getRecordTimeFromArg(t, args[0])
 			if err != nil {
-				out.Err("failed to parse date argument: %s", err.Error())
+				out.Err("%s", err.Error())
 				return
 			}
 
@@ -93,14 +94,21 @@ func showRecord(record *core.Record, formatter *core.Formatter) {
 		project = record.Project.Key
 	}
 
+	note := defaultString
+
+	if record.Note != "" {
+		note = record.Note
+	}
+
 	rows := [][]string{
 		{
 			formatter.TimeString(record.Start),
 			end,
 			project,
 			isBillable,
+			note,
 		},
 	}
 
-	out.Table([]string{"Start", "End", "Project", "Billable"}, rows, nil)
+	out.Table([]string{"Start", "End", "Project", "Billable", "Note"}, rows, nil)
 }
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dominikbraun/timetrace/core"
+	"github.com/dominikbraun/timetrace/out"
+
+	"github.com/spf13/cobra"
+)
+
+type historyOptions struct {
+	limit int
+}
+
+func historyCommand(t *core.Timetrace) *cobra.Command {
+	var options historyOptions
+
+	history := &cobra.Command{
+		Use:   "history",
+		Short: "Show the latest operations that can be undone",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			entries, err := t.History()
+			if err != nil {
+				out.Err("failed to load history: %s", err.Error())
+				return
+			}
+
+			if options.limit > 0 && len(entries) > options.limit {
+				entries = entries[len(entries)-options.limit:]
+			}
+
+			rows := make([][]string, len(entries))
+
+			for i, entry := range entries {
+				changes := make([]string, 0, len(entry.Changes))
+				for _, change := range entry.Changes {
+					changes = append(changes, fmt.Sprintf("%s %s %s", change.Kind, change.Key, change.Action()))
+				}
+				if entry.Target != 0 {
+					changes = append(changes, "#"+strconv.Itoa(entry.Target))
+				}
+
+				undone := defaultBool
+				if entry.Undone {
+					undone = "yes"
+				}
+
+				rows[i] = []string{
+					strconv.Itoa(entry.ID),
+					entry.Time.Format("2006-01-02") + " " + t.Formatter().TimeString(entry.Time),
+					entry.Operation,
+					strings.Join(changes, "\n"),
+					undone,
+				}
+			}
+
+			out.Table([]string{"#", "Time", "Operation", "Changes", "Undone"}, rows, nil)
+		},
+	}
+
+	history.Flags().IntVarP(&options.limit, "limit", "n",
+		10, "number of operations to show, 0 shows all operations")
+
+	return history
+}
@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/dominikbraun/timetrace/core"
+	"github.com/dominikbraun/timetrace/out"
+
+	"github.com/spf13/cobra"
+)
+
+const importConfirmation = "Importing records...Please confirm [y/N]: "
+
+type importOptions struct {
+	format    string
+	dryRun    bool
+	confirmed bool
+}
+
+func importCommand(t *core.Timetrace) *cobra.Command {
+	var options importOptions
+
+	importCmd := &cobra.Command{
+		Use:   "import <FILE>",
+		Short: "Import records from other time trackers",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			file, err := os.Open(args[0])
+			if err != nil {
+				out.Err("failed to open file: %s", err.Error())
+				return
+			}
+			defer file.Close()
+
+			records, err := core.ParseImport(options.format, file, t.Formatter().Location())
+			if err != nil {
+				out.Err("failed to parse %s: %s", args[0], err.Error())
+				return
+			}
+
+			// Always perform a dry run first so that the user can review
+			// the import before anything is written.
+			summary, err := t.Import(records, true)
+			if err != nil {
+				out.Err("failed to import records: %s", err.Error())
+				return
+			}
+
+			showImportSummary(summary, t.Formatter())
+
+			if options.dryRun || len(summary.Imported) == 0 {
+				return
+			}
+
+			if !options.confirmed && !askForConfirmation(importConfirmation) {
+				out.Info("Records NOT imported")
+				return
+			}
+
+			summary, err = t.Import(records, false)
+			if err != nil {
+				out.Err("failed to import records: %s", err.Error())
+				return
+			}
+
+			out.Success("Imported %d records", len(summary.Imported))
+		},
+	}
+
+	importCmd.Flags().StringVar(&options.format, "from", "",
+		fmt.Sprintf("format of the file to import (%s, %s, %s)",
+			core.ImportFormatTogglCsv, core.ImportFormatWatson, core.ImportFormatTimewarrior))
+	_ = importCmd.MarkFlagRequired("from")
+
+	importCmd.Flags().BoolVar(&options.dryRun, "dry-run",
+		false, "only display what would be imported")
+
+	importCmd.Flags().BoolVar(&options.confirmed, "yes",
+		false, "Do not ask for confirmation")
+
+	return importCmd
+}
+
+func showImportSummary(summary *core.ImportSummary, formatter *core.Formatter) {
+	if len(summary.Colliding) > 0 {
+		out.Warn("The following records collide with other records and won't be imported:")
+		out.Table([]string{"#", "Date", "Project", "Start", "End"}, importRows(summary.Colliding, formatter), nil)
+	}
+
+	if len(summary.Rejected) > 0 {
+		rows := importRows(summary.Rejected, formatter)
+		for i, record := range summary.Rejected {
+			rows[i] = append(rows[i], formatter.FormatTags(record.Tags))
+		}
+
+		out.Warn("The following records have invalid or unknown tags and won't be imported:")
+		out.Table([]string{"#", "Date", "Project", "Start", "End", "Tags"}, rows, nil)
+	}
+
+	if len(summary.Skipped) > 0 {
+		out.Warn("%d records are still running and won't be imported", len(summary.Skipped))
+	}
+
+	if len(summary.NewProjects) > 0 {
+		out.Info("New projects: %s", formatter.FormatTags(summary.NewProjects))
+	}
+
+	out.Info("%d records to import", len(summary.Imported))
+}
+
+func importRows(records []*core.Record, formatter *core.Formatter) [][]string {
+	rows := make([][]string, 0, len(records))
+
+	for i, record := range records {
+		end := defaultString
+		if record.End != nil {
+			end = formatter.TimeString(*record.End)
+		}
+
+		rows = append(rows, []string{
+			strconv.Itoa(i + 1),
+			formatter.PrettyDateString(record.Start),
+			record.Project.Key,
+			formatter.TimeString(record.Start),
+			end,
+		})
+	}
+
+	return rows
+}
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dominikbraun/timetrace/core"
+	"github.com/dominikbraun/timetrace/out"
+
+	"github.com/spf13/cobra"
+)
+
+type invoiceOptions struct {
+	projectKey   string
+	startTime    string
+	endTime      string
+	groupBy      string
+	format       string
+	templateFile string
+	filePath     string
+	number       string
+	preview      bool
+}
+
+func invoiceCommand(t *core.Timetrace) *cobra.Command {
+	var options invoiceOptions
+
+	invoice := &cobra.Command{
+		Use:   "invoice",
+		Short: "Create an invoice for all billable records of a project",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var startDate, endDate time.Time
+			var err error
+
+			if options.startTime != "" {
+				startDate, err = t.Formatter().ParseDate(options.startTime)
+				if err != nil {
+					out.Err("failed to parse date: %s", err.Error())
+					return
+				}
+			}
+
+			if options.endTime != "" {
+				endDate, err = t.Formatter().ParseDate(options.endTime)
+				if err != nil {
+					out.Err("failed to parse date: %s", err.Error())
+					return
+				}
+			}
+
+			number := options.number
+			if number == "" {
+				if number, err = t.DefaultInvoiceNumber(options.projectKey); err != nil {
+					out.Err("failed to create invoice: %s", err.Error())
+					return
+				}
+			}
+
+			invoice, err := t.CreateInvoice(number, options.projectKey, startDate, endDate, options.groupBy)
+			if err != nil {
+				out.Err("failed to create invoice: %s", err.Error())
+				return
+			}
+
+			data, err := t.RenderInvoice(invoice, options.format, options.templateFile)
+			if err != nil {
+				out.Err("failed to render invoice: %s", err.Error())
+				return
+			}
+
+			if options.preview {
+				fmt.Print(string(data))
+				return
+			}
+
+			filePath := options.filePath
+			if filePath == "" {
+				filePath = "invoice-" + number + invoiceFileExt(options.format)
+			}
+
+			if err := writeNewFile(filePath, data); err != nil {
+				out.Err("failed to write invoice: %s", err.Error())
+				return
+			}
+
+			if err := t.MarkInvoiced(invoice); err != nil {
+				out.Err("failed to mark records as invoiced: %s", err.Error())
+				return
+			}
+
+			out.Success("Created invoice %s with %d records: %s", number, len(invoice.Records), filePath)
+		},
+	}
+
+	invoice.Flags().StringVarP(&options.projectKey, "project", "p",
+		"", "project to create the invoice for")
+	_ = invoice.MarkFlagRequired("project")
+
+	invoice.Flags().StringVarP(&options.startTime, "start", "s",
+		"", "include records from a given start date <YYYY-MM-DD>")
+
+	invoice.Flags().StringVarP(&options.endTime, "end", "e",
+		"", "include records to a given end date (end is inclusive) <YYYY-MM-DD>")
+
+	invoice.Flags().StringVar(&options.groupBy, "group-by",
+		core.InvoiceGroupByDay, fmt.Sprintf("group line items by %s or %s", core.InvoiceGroupByDay, core.InvoiceGroupByTag))
+
+	invoice.Flags().StringVarP(&options.format, "output", "o",
+		core.InvoiceFormatMarkdown, fmt.Sprintf("output format of the invoice (%s, %s)", core.InvoiceFormatMarkdown, core.InvoiceFormatHtml))
+
+	invoice.Flags().StringVar(&options.templateFile, "template",
+		"", "Go template file to render the invoice with")
+
+	invoice.Flags().StringVarP(&options.filePath, "file", "f",
+		"", "file to write the invoice to")
+
+	invoice.Flags().StringVar(&options.number, "number",
+		"", "invoice number, defaults to <PROJECT>-<YYYYMMDD>")
+
+	invoice.Flags().BoolVar(&options.preview, "preview",
+		false, "print the invoice without writing it or marking records as invoiced")
+
+	return invoice
+}
+
+// writeNewFile writes data to a file that must not exist yet, so that an
+// existing invoice is never overwritten.
+func writeNewFile(filePath string, data []byte) error {
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func invoiceFileExt(format string) string {
+	if format == core.InvoiceFormatHtml {
+		return ".html"
+	}
+
+	return ".md"
+}
@@ -21,6 +21,7 @@ func listCommand(t *core.Timetrace) *cobra.Command {
 
 	list.AddCommand(listProjectsCommand(t))
 	list.AddCommand(listRecordsCommand(t))
+	list.AddCommand(listTagsCommand(t))
 
 	return list
 }
@@ -60,18 +61,45 @@ func listProjectsCommand(t *core.Timetrace) *cobra.Command {
 	return listProjects
 }
 
+func listTagsCommand(t *core.Timetrace) *cobra.Command {
+	listTags := &cobra.Command{
+		Use:   "tags",
+		Short: "List all registered tags",
+		Run: func(cmd *cobra.Command, args []string) {
+			tags, err := t.ListTags()
+			if err != nil {
+				out.Err("failed to list tags: %s", err.Error())
+				return
+			}
+
+			rows := make([][]string, len(tags))
+
+			for i, tag := range tags {
+				rows[i] = []string{strconv.Itoa(i + 1), tag.Key, tag.Description, tag.Color}
+			}
+
+			out.Table([]string{"#", "Key", "Description", "Color"}, rows, nil)
+		},
+	}
+
+	return listTags
+}
+
 type listRecordsOptions struct {
 	isOnlyDisplayingBillable bool
 	projectKeyFilter         string
+	tagFilter                []string
+	isMatchingAllTags        bool
 }
 
 func listRecordsCommand(t *core.Timetrace) *cobra.Command {
 	var options listRecordsOptions
 
 	listRecords := &cobra.Command{
-		Use:   "records {<YYYY-MM-DD>|today|yesterday}",
-		Short: "List all records from a date",
-		Args:  cobra.ExactArgs(1),
+		Use:     "records {<YYYY-MM-DD>|today|yesterday|monday|last monday|3d ago}",
+		Short:   "List all records from a date",
+		Example: "  timetrace list records \"last friday\"\n  timetrace list records -- -3d",
+		Args:    cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			date, err := t.Formatter().ParseDate(args[0])
 			if err != nil {
@@ -93,8 +121,16 @@ func listRecordsCommand(t *core.Timetrace) *cobra.Command {
 				records = filterBillableRecords(records)
 			}
 
+			if len(options.tagFilter) > 0 {
+				records = filterTagRecords(records, options.tagFilter, options.isMatchingAllTags)
+			}
+
 			rows := make([][]string, len(records))
 
+			// Record IDs are numbered per day, so the IDs of each day are
+			// only computed once.
+			ids := make(map[string]string)
+
 			for i, record := range records {
 				end := defaultString
 				if record.End != nil {
@@ -107,21 +143,35 @@ func listRecordsCommand(t *core.Timetrace) *cobra.Command {
 					billable = "yes"
 				}
 
-				rows[i] = make([]string, 7)
-				rows[i][0] = strconv.Itoa(len(records) - i)
-				rows[i][1] = t.Formatter().RecordKey(record)
+				key := t.Formatter().RecordKey(record)
+
+				if _, ok := ids[key]; !ok {
+					dayIDs, err := t.RecordIDs(record.Start)
+					if err != nil {
+						out.Err("failed to get record ID: %s", err.Error())
+						return
+					}
+					for dayKey, id := range dayIDs {
+						ids[dayKey] = id
+					}
+				}
+
+				rows[i] = make([]string, 8)
+				rows[i][0] = ids[key]
+				rows[i][1] = key
 				rows[i][2] = record.Project.Key
 				rows[i][3] = t.Formatter().TimeString(record.Start)
 				rows[i][4] = end
 				rows[i][5] = billable
 				rows[i][6] = t.Formatter().FormatTags(record.Tags)
+				rows[i][7] = record.Note
 			}
 
-			footer := make([]string, 7)
+			footer := make([]string, 8)
 			footer[len(footer)-2] = "Total: "
-			footer[len(footer)-1] = t.Formatter().FormatDuration(getTotalTrackedTime(records))
+			footer[len(footer)-1] = t.Formatter().FormatDuration(getTotalTrackedTime(records, date))
 
-			out.Table([]string{"#", "Key", "Project", "Start", "End", "Billable", "Tags"}, rows, footer)
+			out.Table([]string{"ID", "Key", "Project", "Start", "End", "Billable", "Tags", "Note"}, rows, footer)
 		},
 	}
 
@@ -131,6 +181,12 @@ func listRecordsCommand(t *core.Timetrace) *cobra.Command {
 	listRecords.Flags().StringVarP(&options.projectKeyFilter, "project", "p",
 		"", "filter by project key")
 
+	listRecords.Flags().StringSliceVarP(&options.tagFilter, "tag", "t",
+		nil, "filter by tags, prefix a tag with ! to exclude it")
+
+	listRecords.Flags().BoolVar(&options.isMatchingAllTags, "all-tags",
+		false, "only display records having all given tags")
+
 	return listRecords
 }
 
@@ -154,6 +210,17 @@ func filterProjectRecords(records []*core.Record, key string) []*core.Record {
 	return projectRecords
 }
 
+func filterTagRecords(records []*core.Record, tags []string, all bool) []*core.Record {
+	filter := core.FilterByTag(tags, all)
+	tagRecords := []*core.Record{}
+	for _, record := range records {
+		if filter(record) {
+			tagRecords = append(tagRecords, record)
+		}
+	}
+	return tagRecords
+}
+
 func removeModules(allProjects []*core.Project) []*core.Project {
 	var parentProjects []*core.Project
 	for _, p := range allProjects {
@@ -165,10 +232,12 @@ func removeModules(allProjects []*core.Project) []*core.Project {
 	return parentProjects
 }
 
-func getTotalTrackedTime(records []*core.Record) time.Duration {
+// getTotalTrackedTime returns the time tracked on the given date. Only the part
+// of records spanning midnight that belongs to that date is counted.
+func getTotalTrackedTime(records []*core.Record, date time.Time) time.Duration {
 	var totalTime time.Duration
 	for _, record := range records {
-		totalTime += record.Duration()
+		totalTime += record.DurationOn(date)
 	}
 	return totalTime
 }
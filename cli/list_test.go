@@ -119,6 +119,7 @@ func TestFilterProjectRecords(t *testing.T) {
 func TestTotalTrackedTime(t *testing.T) {
 	tt := []struct {
 		records  []*core.Record
+		date     time.Time
 		expected time.Duration
 	}{
 		{records: []*core.Record{
@@ -135,11 +136,25 @@ func TestTotalTrackedTime(t *testing.T) {
 				End:   timePtr(time.Date(2021, 06, 07, 17, 10, 00, 00, time.Local)), // 5:10PM
 			},
 		},
+			date:     time.Date(2021, 06, 07, 0, 00, 00, 00, time.Local),
+			expected: time.Duration(time.Hour),
+		},
+		{records: []*core.Record{
+			{
+				Start: time.Date(2021, 06, 06, 23, 00, 00, 00, time.Local),          // 11:00PM the day before
+				End:   timePtr(time.Date(2021, 06, 07, 00, 30, 00, 00, time.Local)), // 12:30AM
+			},
+			{
+				Start: time.Date(2021, 06, 07, 23, 30, 00, 00, time.Local),         // 11:30PM
+				End:   timePtr(time.Date(2021, 06, 8, 01, 00, 00, 00, time.Local)), // 1:00AM the day after
+			},
+		},
+			date:     time.Date(2021, 06, 07, 0, 00, 00, 00, time.Local),
 			expected: time.Duration(time.Hour),
 		},
 	}
 	for _, test := range tt {
-		totalTime := getTotalTrackedTime(test.records)
+		totalTime := getTotalTrackedTime(test.records, test.date)
 		if totalTime != test.expected {
 			t.Fatalf("error when %v != %v", totalTime, test.expected)
 		}
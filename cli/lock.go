@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"time"
+
+	"github.com/dominikbraun/timetrace/core"
+	"github.com/dominikbraun/timetrace/out"
+
+	"github.com/spf13/cobra"
+)
+
+const lockAllConfirmation = "Locking all records...Please confirm [y/N]: "
+
+type lockOptions struct {
+	projectKey string
+	startTime  string
+	endTime    string
+	unlock     bool
+	confirmed  bool
+}
+
+func lockCommand(t *core.Timetrace) *cobra.Command {
+	var options lockOptions
+
+	lock := &cobra.Command{
+		Use:   "lock",
+		Short: "Lock records so that they can't be edited or deleted",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var startDate, endDate time.Time
+			var err error
+
+			if options.startTime != "" {
+				startDate, err = t.Formatter().ParseDate(options.startTime)
+				if err != nil {
+					out.Err("failed to parse date: %s", err.Error())
+					return
+				}
+			}
+
+			if options.endTime != "" {
+				endDate, err = t.Formatter().ParseDate(options.endTime)
+				if err != nil {
+					out.Err("failed to parse date: %s", err.Error())
+					return
+				}
+			}
+
+			// Without a date range, all records of the project or even the
+			// entire store would be locked.
+			allRecords := options.startTime == "" && options.endTime == ""
+
+			if allRecords && !options.unlock && !options.confirmed && !askForConfirmation(lockAllConfirmation) {
+				out.Info("Records NOT locked")
+				return
+			}
+
+			records, err := t.LockRecords(startDate, endDate, options.projectKey, !options.unlock)
+			if err != nil {
+				out.Err("failed to lock records: %s", err.Error())
+				return
+			}
+
+			if options.unlock {
+				out.Success("Unlocked %d records", len(records))
+				return
+			}
+
+			out.Success("Locked %d records", len(records))
+		},
+	}
+
+	lock.Flags().StringVarP(&options.startTime, "start", "s",
+		"", "lock records from a given start date <YYYY-MM-DD>")
+
+	lock.Flags().StringVarP(&options.endTime, "end", "e",
+		"", "lock records to a given end date (end is inclusive) <YYYY-MM-DD>")
+
+	lock.Flags().StringVarP(&options.projectKey, "project", "p",
+		"", "only lock records of a specific project")
+
+	lock.Flags().BoolVar(&options.unlock, "unlock",
+		false, "unlock the records instead, invoiced records remain locked")
+
+	lock.Flags().BoolVar(&options.confirmed, "yes",
+		false, "do not ask for confirmation when locking all records")
+
+	return lock
+}
@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"github.com/dominikbraun/timetrace/core"
+	"github.com/dominikbraun/timetrace/out"
+
+	"github.com/spf13/cobra"
+)
+
+func pauseCommand(t *core.Timetrace) *cobra.Command {
+	pause := &cobra.Command{
+		Use:   "pause [PROJECT KEY]",
+		Short: "Pause tracking your time without stopping the record",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var projectKey string
+			if len(args) > 0 {
+				projectKey = args[0]
+			}
+
+			if err := t.Pause(projectKey); err != nil {
+				out.Err("failed to pause tracking: %s", err.Error())
+				return
+			}
+
+			out.Success("Paused tracking time")
+		},
+	}
+
+	return pause
+}
@@ -18,19 +18,32 @@ type reportOptions struct {
 	filePath      string
 	startTime     string
 	endTime       string
+	delimiter     string
+	groupBy       string
+	tags          []string
+	allTags       bool
+	timeZone      string
 }
 
 func generateReportCommand(t *core.Timetrace) *cobra.Command {
 	var options reportOptions
 
 	report := &cobra.Command{
-		Use:    "report",
-		Short:  "Report allows to view or output tracked records as defined report",
-		Hidden: true,
+		Use:   "report",
+		Short: "Report allows to view or output tracked records as defined report",
 		Run: func(cmd *cobra.Command, args []string) {
 			var startDate, endDate time.Time
 			var formatErr error
 
+			if options.timeZone != "" {
+				location, err := time.LoadLocation(options.timeZone)
+				if err != nil {
+					out.Err("failed to load time zone: %s", err.Error())
+					return
+				}
+				t.Formatter().SetDisplayLocation(location)
+			}
+
 			if options.startTime != "" {
 				startDate, formatErr = t.Formatter().ParseDate(options.startTime)
 				if formatErr != nil {
@@ -52,12 +65,14 @@ func generateReportCommand(t *core.Timetrace) *cobra.Command {
 				// this will ignore records which end time to not set
 				// so current tracked times for example
 				core.FilterNoneNilEndTime,
-				core.FilterByTimeRange(startDate, endDate),
 			}
 
 			if options.projectKey != "" {
 				filter = append(filter, core.FilterByProject(options.projectKey))
 			}
+			if len(options.tags) > 0 {
+				filter = append(filter, core.FilterByTag(options.tags, options.allTags))
+			}
 			// wont hurt table will just be empty but makes sense to let the user know
 			if options.isBillable && options.isNonBillable {
 				out.Err("cannot filter for billable and none billable records")
@@ -70,9 +85,20 @@ func generateReportCommand(t *core.Timetrace) *cobra.Command {
 				filter = append(filter, core.FilterBillable(false))
 			}
 
-			report, err := t.Report(filter...)
+			report, err := t.DailyReport(startDate, endDate, filter...)
 			if err != nil {
 				out.Err(err.Error())
+				return
+			}
+
+			if options.groupBy != "" {
+				summary, err := report.Summary(options.groupBy, startDate, endDate)
+				if err != nil {
+					out.Err("failed to create summary: %s", err.Error())
+					return
+				}
+				writeSummary(t, summary, options)
+				return
 			}
 
 			// check what to do with the report
@@ -83,21 +109,45 @@ func generateReportCommand(t *core.Timetrace) *cobra.Command {
 				data, err := report.Json()
 				if err != nil {
 					out.Err(err.Error())
+					return
+				}
+				if err := t.WriteReport(options.filePath, data); err != nil {
+					out.Err("failed to write report: %s", err.Error())
+					return
+				}
+			case "csv":
+				delimiter := []rune(options.delimiter)
+				if len(delimiter) != 1 {
+					out.Err("the csv delimiter must be a single character")
+					return
+				}
+				data, err := report.Csv(delimiter[0])
+				if err != nil {
+					out.Err(err.Error())
+					return
+				}
+				if err := t.WriteReport(options.filePath, data); err != nil {
+					out.Err("failed to write report: %s", err.Error())
+					return
 				}
-				t.WriteReport(options.filePath, data)
 			default:
 				projects, total := report.Table()
+				amount := t.Formatter().FormatAmounts(report.TotalAmount())
 				out.Table(
-					[]string{"Project", "Module", "Date", "Start", "End", "Billable", "Total"},
+					[]string{"Project", "Module", "Date", "Start", "End", "Billable", "Note", "Total", "Amount"},
 					projects,
-					[]string{"", "", "", "", "", "TOTAL", total},
+					[]string{"", "", "", "", "", "", "TOTAL", total, amount},
 					out.TableWithCellMerge(0), // merge cells over "Project" (index:0) column
 					out.TableFooterColor(
 						tablewriter.Colors{}, tablewriter.Colors{},
-						tablewriter.Colors{}, tablewriter.Colors{}, tablewriter.Colors{},
+						tablewriter.Colors{}, tablewriter.Colors{}, tablewriter.Colors{}, tablewriter.Colors{},
 						tablewriter.Colors{tablewriter.Bold},          // text "TOTAL"
-						tablewriter.Colors{tablewriter.FgGreenColor}), // digit of "TOTAL"
+						tablewriter.Colors{tablewriter.FgGreenColor},  // digit of "TOTAL"
+						tablewriter.Colors{tablewriter.FgGreenColor}), // billable amount
 				)
+				if tags := report.TagTable(); len(tags) > 0 {
+					out.Table([]string{"Tag", "Total"}, tags, nil)
+				}
 			}
 		},
 	}
@@ -118,10 +168,57 @@ func generateReportCommand(t *core.Timetrace) *cobra.Command {
 		"", "filter records by a specific project")
 
 	report.Flags().StringVarP(&options.outputFormat, "output", "o",
-		"print table", "output format for report file (json)")
+		"print table", "output format for report file (json, csv)")
 
 	report.Flags().StringVarP(&options.filePath, "file", "f",
 		"", "file to write report to")
 
+	report.Flags().StringVar(&options.delimiter, "delimiter",
+		",", "field delimiter for csv output")
+
+	report.Flags().StringSliceVarP(&options.tags, "tag", "t",
+		nil, "filter records by tags, prefix a tag with ! to exclude it")
+
+	report.Flags().BoolVar(&options.allTags, "all-tags",
+		false, "only include records having all given tags")
+
+	report.Flags().StringVar(&options.groupBy, "group-by",
+		"", "summarize tracked time by day, week, month, project or tag; week shows one row per project and week with a column per weekday")
+
+	report.Flags().StringVar(&options.timeZone, "timezone",
+		"", "time zone to display times in, e.g. Europe/Berlin (defaults to the home time zone)")
+
 	return report
 }
+
+// writeSummary prints the summary as table or writes it to a file, depending on
+// the output format.
+func writeSummary(t *core.Timetrace, summary *core.Summary, options reportOptions) {
+	var data []byte
+	var err error
+
+	switch options.outputFormat {
+	case "json":
+		data, err = summary.Json()
+	case "csv":
+		delimiter := []rune(options.delimiter)
+		if len(delimiter) != 1 {
+			out.Err("the csv delimiter must be a single character")
+			return
+		}
+		data, err = summary.Csv(delimiter[0])
+	default:
+		header, rows, footer := summary.Table()
+		out.Table(header, rows, footer)
+		return
+	}
+
+	if err != nil {
+		out.Err(err.Error())
+		return
+	}
+
+	if err := t.WriteReport(options.filePath, data); err != nil {
+		out.Err("failed to write report: %s", err.Error())
+	}
+}
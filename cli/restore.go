@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/dominikbraun/timetrace/config"
+	"github.com/dominikbraun/timetrace/core"
+	"github.com/dominikbraun/timetrace/out"
+
+	"github.com/spf13/cobra"
+)
+
+type restoreOptions struct {
+	force bool
+}
+
+func restoreCommand(t *core.Timetrace) *cobra.Command {
+	var options restoreOptions
+
+	restore := &cobra.Command{
+		Use:   "restore <FILE>",
+		Short: "Restore projects, records and the config from an archive",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			file, err := os.Open(args[0])
+			if err != nil {
+				out.Err("failed to open archive: %s", err.Error())
+				return
+			}
+			defer file.Close()
+
+			summary, err := t.Restore(file, options.force)
+			if errors.Is(err, core.ErrArchiveConflicts) {
+				out.Err("failed to restore: %s", err.Error())
+				out.Warn("conflicting projects and records: %s", t.Formatter().FormatTags(summary.Conflicts))
+				out.Info("use --force to overwrite them")
+				return
+			} else if err != nil {
+				out.Err("failed to restore: %s", err.Error())
+				return
+			}
+
+			if summary.Config != nil {
+				restoreConfig(summary.Config, options.force)
+			}
+
+			out.Success("Restored %d projects, %d records and %d tags", summary.Projects, summary.Records, summary.Tags)
+		},
+	}
+
+	restore.Flags().BoolVar(&options.force, "force", false, "Overwrite conflicting projects, records and config")
+
+	return restore
+}
+
+// restoreConfig writes the config file from an archive. An existing config file
+// with different contents is only overwritten if force is set.
+func restoreConfig(data []byte, force bool) {
+	path := config.FilePath()
+
+	existing, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		out.Err("failed to read config: %s", err.Error())
+		return
+	}
+
+	if err == nil && !bytes.Equal(existing, data) && !force {
+		out.Warn("config NOT restored because %s already exists, use --force to overwrite it", path)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		out.Err("failed to restore config: %s", err.Error())
+		return
+	}
+
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		out.Err("failed to restore config: %s", err.Error())
+	}
+}
@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"github.com/dominikbraun/timetrace/core"
+	"github.com/dominikbraun/timetrace/out"
+
+	"github.com/spf13/cobra"
+)
+
+func resumeCommand(t *core.Timetrace) *cobra.Command {
+	resume := &cobra.Command{
+		Use:   "resume [PROJECT KEY]",
+		Short: "Resume tracking your time after a pause",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var projectKey string
+			if len(args) > 0 {
+				projectKey = args[0]
+			}
+
+			if err := t.Resume(projectKey); err != nil {
+				out.Err("failed to resume tracking: %s", err.Error())
+				return
+			}
+
+			out.Success("Resumed tracking time")
+		},
+	}
+
+	return resume
+}
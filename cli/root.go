@@ -1,6 +1,9 @@
 package cli
 
 import (
+	"os"
+	"strings"
+
 	"github.com/dominikbraun/timetrace/core"
 
 	"github.com/spf13/cobra"
@@ -11,6 +14,11 @@ const (
 	defaultBool   = "no"
 )
 
+// modifiesStoreAnnotation marks commands that modify the store. These commands
+// hold the store lock while running, so that concurrent timetrace invocations
+// don't overwrite each other's changes.
+const modifiesStoreAnnotation = "modifies-store"
+
 func RootCommand(t *core.Timetrace, version string) *cobra.Command {
 	root := &cobra.Command{
 		Use:           "timetrace",
@@ -19,23 +27,73 @@ func RootCommand(t *core.Timetrace, version string) *cobra.Command {
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			return t.EnsureDirectories()
+			if err := t.EnsureDirectories(); err != nil {
+				return err
+			}
+			if modifiesStore(cmd) {
+				return t.Lock()
+			}
+			return nil
+		},
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+			if !modifiesStore(cmd) {
+				return nil
+			}
+			// The command line describes the operation in the history.
+			if err := t.CommitOperation(strings.Join(os.Args[1:], " ")); err != nil {
+				return err
+			}
+			return t.Unlock()
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			_ = cmd.Help()
 		},
 	}
 
-	root.AddCommand(createCommand(t))
+	root.AddCommand(modifying(createCommand(t)))
 	root.AddCommand(getCommand(t))
 	root.AddCommand(listCommand(t))
-	root.AddCommand(editCommand(t))
-	root.AddCommand(deleteCommand(t))
-	root.AddCommand(startCommand(t))
+	root.AddCommand(modifying(editCommand(t)))
+	root.AddCommand(modifying(deleteCommand(t)))
+	root.AddCommand(modifying(startCommand(t)))
 	root.AddCommand(statusCommand(t))
-	root.AddCommand(stopCommand(t))
+	root.AddCommand(modifying(stopCommand(t)))
+	root.AddCommand(modifying(switchCommand(t)))
+	root.AddCommand(modifying(continueCommand(t)))
+	root.AddCommand(modifying(pauseCommand(t)))
+	root.AddCommand(modifying(resumeCommand(t)))
 	root.AddCommand(generateReportCommand(t))
+	root.AddCommand(modifying(importCommand(t)))
+	root.AddCommand(exportCommand(t))
+	root.AddCommand(modifying(restoreCommand(t)))
+	root.AddCommand(modifying(invoiceCommand(t)))
+	root.AddCommand(modifying(lockCommand(t)))
+	root.AddCommand(modifying(undoCommand(t)))
+	root.AddCommand(modifying(redoCommand(t)))
+	root.AddCommand(historyCommand(t))
 	root.AddCommand(versionCommand(version))
 
 	return root
 }
+
+// modifying marks the command and all of its subcommands as modifying the store.
+func modifying(cmd *cobra.Command) *cobra.Command {
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+	cmd.Annotations[modifiesStoreAnnotation] = "true"
+
+	return cmd
+}
+
+// modifiesStore checks if the command or one of its parents has been marked as
+// modifying the store.
+func modifiesStore(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if _, ok := c.Annotations[modifiesStoreAnnotation]; ok {
+			return true
+		}
+	}
+
+	return false
+}
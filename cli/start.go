@@ -3,7 +3,9 @@ package cli
 import (
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/dominikbraun/timetrace/config"
 	"github.com/dominikbraun/timetrace/core"
 	"github.com/dominikbraun/timetrace/out"
 
@@ -15,6 +17,9 @@ const TagsPrefix = "+"
 type startOptions struct {
 	isBillable    bool
 	isNonBillable bool // Used for overwriting `billable: true` in the project config.
+	isParallel    bool
+	note          string
+	at            string
 }
 
 func startCommand(t *core.Timetrace) *cobra.Command {
@@ -28,22 +33,7 @@ func startCommand(t *core.Timetrace) *cobra.Command {
 			projectKey := args[0]
 			tags := args[1:]
 
-			// Limit number of tags to 3
-			if len(tags) > 3 {
-				out.Err("Failed to start tracking: At most 3 tags are allowed, got %v tags", len(tags))
-				return
-			}
-
-			isBillable := options.isBillable
-
-			// If there is a default configuration for the project key, use that configuration.
-			if projectConfig, ok := t.Config().Projects[projectKey]; ok {
-				isBillable = projectConfig.Billable
-			}
-
-			if options.isNonBillable {
-				isBillable = false
-			}
+			isBillable := options.billable(t.Config(), projectKey)
 
 			tagNames, err := extractTagNames(tags)
 			if err != nil {
@@ -51,7 +41,15 @@ func startCommand(t *core.Timetrace) *cobra.Command {
 				return
 			}
 
-			if err := t.Start(projectKey, isBillable, tagNames); err != nil {
+			var at time.Time
+			if options.at != "" {
+				if at, err = t.Formatter().ParseDateTime(options.at); err != nil {
+					out.Err("failed to parse start time: %s", err.Error())
+					return
+				}
+			}
+
+			if err := t.Start(projectKey, isBillable, tagNames, options.isParallel, options.note, at); err != nil {
 				out.Err("failed to start tracking: %s", err.Error())
 				return
 			}
@@ -66,9 +64,35 @@ func startCommand(t *core.Timetrace) *cobra.Command {
 	start.Flags().BoolVar(&options.isNonBillable, "non-billable",
 		false, `mark tracked time as non-billable if the project is configured as billable`)
 
+	start.Flags().BoolVar(&options.isParallel, "parallel",
+		false, `track time in parallel to the running records`)
+
+	start.Flags().StringVarP(&options.note, "note", "m",
+		"", `add a note describing the work done`)
+
+	start.Flags().StringVar(&options.at, "at",
+		"", `start tracking at the given time, e.g. 9:30, "10 min ago" or "yesterday 17:00"`)
+
 	return start
 }
 
+// billable returns whether a new record of the given project is billable. If
+// there is a default configuration for the project key, it overrides the
+// --billable flag. The --non-billable flag overrides both.
+func (options startOptions) billable(config *config.Config, projectKey string) bool {
+	isBillable := options.isBillable
+
+	if projectConfig, ok := config.Projects[projectKey]; ok {
+		isBillable = projectConfig.Billable
+	}
+
+	if options.isNonBillable {
+		isBillable = false
+	}
+
+	return isBillable
+}
+
 func extractTagNames(tagsWithPrefix []string) ([]string, error) {
 	tagNames := make([]string, 0)
 
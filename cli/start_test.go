@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/dominikbraun/timetrace/config"
+)
+
+func TestStartBillable(t *testing.T) {
+	cfg := &config.Config{
+		Projects: map[string]config.Project{
+			"make-coffee":   {Billable: true},
+			"clean-kitchen": {Billable: false},
+		},
+	}
+
+	tests := map[string]struct {
+		projectKey string
+		options    startOptions
+		expected   bool
+	}{
+		"no configuration": {
+			projectKey: "grind-beans@make-coffee",
+		},
+		"billable flag": {
+			projectKey: "grind-beans@make-coffee",
+			options:    startOptions{isBillable: true},
+			expected:   true,
+		},
+		"billable by configuration": {
+			projectKey: "make-coffee",
+			expected:   true,
+		},
+		"non-billable flag overrides configuration": {
+			projectKey: "make-coffee",
+			options:    startOptions{isNonBillable: true},
+		},
+		"non-billable configuration overrides billable flag": {
+			projectKey: "clean-kitchen",
+			options:    startOptions{isBillable: true},
+		},
+	}
+
+	for name, tc := range tests {
+		if billable := tc.options.billable(cfg, tc.projectKey); billable != tc.expected {
+			t.Errorf("%s: expected billable %t, got %t", name, tc.expected, billable)
+		}
+	}
+}
@@ -13,10 +13,17 @@ import (
 )
 
 type statusReport struct {
-	Project            string `json:"project"`
-	TrackedTimeCurrent string `json:"trackedTimeCurrent"`
-	TrackedTimeToday   string `json:"trackedTimeToday"`
-	BreakTimeToday     string `json:"breakTimeToday"`
+	Project            string                `json:"project"`
+	TrackedTimeCurrent string                `json:"trackedTimeCurrent"`
+	TrackedTimeToday   string                `json:"trackedTimeToday"`
+	BreakTimeToday     string                `json:"breakTimeToday"`
+	Running            []runningRecordStatus `json:"running,omitempty"`
+}
+
+// runningRecordStatus represents one of multiple parallel running records.
+type runningRecordStatus struct {
+	Project     string `json:"project"`
+	TrackedTime string `json:"trackedTime"`
 }
 
 type statusOptions struct {
@@ -60,6 +67,19 @@ func statusCommand(t *core.Timetrace) *cobra.Command {
 				statusReport.TrackedTimeCurrent = t.Formatter().FormatDuration(*report.TrackedTimeCurrent)
 			}
 
+			if len(report.Running) > 1 {
+				for _, record := range report.Running {
+					project := defaultString
+					if record.Project != nil {
+						project = record.Project.Key
+					}
+					statusReport.Running = append(statusReport.Running, runningRecordStatus{
+						Project:     project,
+						TrackedTime: t.Formatter().FormatDuration(record.Duration()),
+					})
+				}
+			}
+
 			if options.format != "" {
 				format := options.format
 				format = strings.ReplaceAll(format, "{project}", statusReport.Project)
@@ -96,6 +116,17 @@ func statusCommand(t *core.Timetrace) *cobra.Command {
 				},
 			}
 
+			// If multiple parallel records are running, print one row for
+			// each record. The daily totals are only printed once.
+			if len(statusReport.Running) > 0 {
+				rows = make([][]string, len(statusReport.Running))
+				for i, running := range statusReport.Running {
+					rows[i] = []string{running.Project, running.TrackedTime, "", ""}
+				}
+				rows[0][2] = statusReport.TrackedTimeToday
+				rows[0][3] = statusReport.BreakTimeToday
+			}
+
 			out.Table([]string{"Current project", "Worked since start", "Worked today", "Breaks"}, rows, nil)
 		},
 	}
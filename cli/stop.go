@@ -1,19 +1,53 @@
 package cli
 
 import (
+	"errors"
+	"time"
+
 	"github.com/dominikbraun/timetrace/core"
 	"github.com/dominikbraun/timetrace/out"
 
 	"github.com/spf13/cobra"
 )
 
+type stopOptions struct {
+	at string
+}
+
 func stopCommand(t *core.Timetrace) *cobra.Command {
+	var options stopOptions
+
 	stop := &cobra.Command{
-		Use:   "stop",
+		Use:   "stop [<PROJECT KEY>|<RECORD KEY>|@ID]",
 		Short: "Stop tracking your time",
-		Args:  cobra.ExactArgs(0),
+		Args:  cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := t.Stop(); err != nil {
+			var projectKey string
+			if len(args) > 0 {
+				projectKey = args[0]
+			}
+
+			var at time.Time
+			if options.at != "" {
+				var err error
+				if at, err = t.Formatter().ParseDateTime(options.at); err != nil {
+					out.Err("failed to parse end time: %s", err.Error())
+					return
+				}
+			}
+
+			// An argument that isn't a project may reference a record, which
+			// is required to stop one of multiple records of the same project.
+			if recordKey, ok := runningRecordFromArg(t, projectKey); ok {
+				if err := t.StopRecord(recordKey, at); err != nil {
+					out.Err("failed to stop tracking: %s", err.Error())
+					return
+				}
+				out.Success("Stopped tracking time")
+				return
+			}
+
+			if err := t.Stop(projectKey, at); err != nil {
 				out.Err("failed to stop tracking: %s", err.Error())
 				return
 			}
@@ -22,5 +56,27 @@ func stopCommand(t *core.Timetrace) *cobra.Command {
 		},
 	}
 
+	stop.Flags().StringVar(&options.at, "at",
+		"", `stop tracking at the given time, e.g. 17:30 or "10 min ago"`)
+
 	return stop
 }
+
+// runningRecordFromArg returns the start time of the record referenced by the
+// given argument, unless the argument is empty or the key of a project.
+func runningRecordFromArg(t *core.Timetrace, arg string) (time.Time, bool) {
+	if arg == "" {
+		return time.Time{}, false
+	}
+
+	if _, err := t.LoadProject(arg); !errors.Is(err, core.ErrProjectNotFound) {
+		return time.Time{}, false
+	}
+
+	recordKey, err := getRecordTimeFromArg(t, arg)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return recordKey, true
+}
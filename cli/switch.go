@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"github.com/dominikbraun/timetrace/core"
+	"github.com/dominikbraun/timetrace/out"
+
+	"github.com/spf13/cobra"
+)
+
+type switchOptions struct {
+	isBillable    bool
+	isNonBillable bool
+	note          string
+}
+
+func switchCommand(t *core.Timetrace) *cobra.Command {
+	var options switchOptions
+
+	switchCmd := &cobra.Command{
+		Use:   "switch <PROJECT KEY> [+TAG1, +TAG2, ...]",
+		Short: "Stop the current record and start tracking another project",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			projectKey := args[0]
+
+			if options.isBillable && options.isNonBillable {
+				out.Err("cannot mark the record as billable and non-billable")
+				return
+			}
+
+			tagNames, err := extractTagNames(args[1:])
+			if err != nil {
+				out.Err("failed to switch tracking: %s", err.Error())
+				return
+			}
+
+			// Without flags, the billable default of the project is used.
+			var isBillable *bool
+			if options.isBillable || options.isNonBillable {
+				isBillable = &options.isBillable
+			}
+
+			if err := t.Switch(projectKey, tagNames, isBillable, options.note); err != nil {
+				out.Err("failed to switch tracking: %s", err.Error())
+				return
+			}
+
+			out.Success("Switched tracking to %s", projectKey)
+		},
+	}
+
+	switchCmd.Flags().BoolVarP(&options.isBillable, "billable", "b",
+		false, `mark tracked time as billable`)
+
+	switchCmd.Flags().BoolVar(&options.isNonBillable, "non-billable",
+		false, `mark tracked time as non-billable if the project is configured as billable`)
+
+	switchCmd.Flags().StringVarP(&options.note, "note", "m",
+		"", `add a note describing the work done`)
+
+	return switchCmd
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"strconv"
+
+	"github.com/dominikbraun/timetrace/core"
+	"github.com/dominikbraun/timetrace/out"
+
+	"github.com/spf13/cobra"
+)
+
+type undoOptions struct {
+	force bool
+}
+
+func undoCommand(t *core.Timetrace) *cobra.Command {
+	var options undoOptions
+
+	undo := &cobra.Command{
+		Use:   "undo [<N>]",
+		Short: "Undo the latest or the latest N operations",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			n, err := operationCountFromArgs(args)
+			if err != nil {
+				out.Err("failed to parse number of operations: %s", err.Error())
+				return
+			}
+
+			entries, err := t.Undo(n, options.force)
+			for _, entry := range entries {
+				out.Success("Undid #%d: %s", entry.ID, entry.Operation)
+			}
+			if err != nil {
+				out.Err("failed to undo: %s", err.Error())
+				return
+			}
+		},
+	}
+
+	undo.Flags().BoolVar(&options.force, "force",
+		false, "undo even if the affected resources have been changed outside of timetrace")
+
+	return undo
+}
+
+func redoCommand(t *core.Timetrace) *cobra.Command {
+	var options undoOptions
+
+	redo := &cobra.Command{
+		Use:   "redo [<N>]",
+		Short: "Redo the latest or the latest N undone operations",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			n, err := operationCountFromArgs(args)
+			if err != nil {
+				out.Err("failed to parse number of operations: %s", err.Error())
+				return
+			}
+
+			entries, err := t.Redo(n, options.force)
+			for _, entry := range entries {
+				out.Success("Redid #%d: %s", entry.ID, entry.Operation)
+			}
+			if err != nil {
+				out.Err("failed to redo: %s", err.Error())
+				return
+			}
+		},
+	}
+
+	redo.Flags().BoolVar(&options.force, "force",
+		false, "redo even if the affected resources have been changed outside of timetrace")
+
+	return redo
+}
+
+// operationCountFromArgs returns the number of operations to undo or redo,
+// which defaults to 1.
+func operationCountFromArgs(args []string) (int, error) {
+	if len(args) == 0 {
+		return 1, nil
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, err
+	}
+
+	if n < 1 {
+		return 0, strconv.ErrRange
+	}
+
+	return n, nil
+}
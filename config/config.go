@@ -2,24 +2,71 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
+// Available storage backends. The backend is selected using the Store setting:
+// A value prefixed with "sqlite:" selects the SQLite backend, any other value
+// selects the JSON file tree.
+const (
+	StoreBackendJSON   = "json"
+	StoreBackendSQLite = "sqlite"
+)
+
+const sqliteStorePrefix = StoreBackendSQLite + ":"
+
 type Config struct {
 	Store           string             `json:"store"`
 	Use12Hours      bool               `json:"use12hours"`
 	UseDecimalHours string             `json:"usedecimalhours"` //"On", "Off", "Both" valid values
 	Editor          string             `json:"editor"`
 	ReportPath      string             `json:"report-path"`
+	StrictTags      bool               `json:"stricttags"` // only allow registered tags
+	LockTimeout     time.Duration      `json:"locktimeout"`
+	SplitAtMidnight bool               `json:"splitatmidnight"` // split records into one record per day when stopping
+	TimeZone        string             `json:"timezone"`        // IANA name of the home time zone, e.g. "Europe/Berlin"
 	Projects        map[string]Project `json:"projects"`
 }
 
+// Project contains per-project settings. Modules can be configured using their
+// full key, e.g. "grind-beans@make-coffee". A module without a rate uses the
+// rate of its parent project.
 type Project struct {
-	Billable bool `json:"billable"`
+	Billable bool    `json:"billable"`
+	Rate     float64 `json:"rate"`
+	Currency string  `json:"currency"`
 }
 
 var cached *Config
 
+// Location returns the configured home time zone. Records are filed and days
+// are calculated in this time zone. If no time zone is configured, the local
+// time zone is returned.
+func (c *Config) Location() (*time.Location, error) {
+	if c == nil || c.TimeZone == "" {
+		return time.Local, nil
+	}
+
+	return time.LoadLocation(c.TimeZone)
+}
+
+// StoreLocation returns the configured storage backend and its location. For
+// the JSON backend, the location is the root directory of the file tree. For
+// the SQLite backend, it is the path of the database file. An empty location
+// means that the default location should be used.
+func (c *Config) StoreLocation() (backend string, location string) {
+	if strings.HasPrefix(c.Store, sqliteStorePrefix) {
+		return StoreBackendSQLite, strings.TrimPrefix(c.Store, sqliteStorePrefix)
+	}
+
+	return StoreBackendJSON, c.Store
+}
+
 // FromFile reads a configuration file called config.yml and returns it as a
 // Config instance. If no configuration file is found, nil and no error will be
 // returned. The configuration must live in one of the following directories:
@@ -54,6 +101,19 @@ func FromFile() (*Config, error) {
 	return cached, nil
 }
 
+// FilePath returns the path of the configuration file read by FromFile. If no
+// configuration file has been found, the path of the configuration file within
+// $HOME/.timetrace is returned.
+func FilePath() string {
+	if path := viper.ConfigFileUsed(); path != "" {
+		return path
+	}
+
+	homeDir, _ := os.UserHomeDir()
+
+	return filepath.Join(homeDir, ".timetrace", "config.yaml")
+}
+
 // Get returns the parsed configuration. The fields of this configuration either
 // contain values specified by the user or the zero value of the respective data
 // type, e.g. "" for an un-configured string.
@@ -0,0 +1,484 @@
+package core
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"time"
+)
+
+// ArchiveVersion is the version of the archive layout written by Export. It
+// has to be increased whenever the layout changes in an incompatible way.
+const ArchiveVersion = 1
+
+const (
+	archiveManifestName = "manifest.json"
+	archiveConfigName   = "config.yaml"
+	archiveProjectsDir  = "projects"
+	archiveRecordsDir   = "records"
+	archiveTagsDir      = "tags"
+	archiveRecordLayout = "2006-01-02-15-04"
+)
+
+var (
+	ErrInvalidArchive     = errors.New("invalid archive")
+	ErrUnsupportedArchive = errors.New("unsupported archive version")
+	ErrArchiveConflicts   = errors.New("archive conflicts with existing projects or records")
+)
+
+// ArchiveManifest describes the contents of an archive created by Export.
+type ArchiveManifest struct {
+	Version  int       `json:"version"`
+	Created  time.Time `json:"created"`
+	Projects int       `json:"projects"`
+	Records  int       `json:"records"`
+	Tags     int       `json:"tags,omitempty"`
+	Config   bool      `json:"config"`
+}
+
+// RestoreSummary describes the outcome of a restore.
+type RestoreSummary struct {
+	Manifest ArchiveManifest
+	// Projects, Records and Tags contain the number of restored resources.
+	// Resources that already exist with the same contents are not counted.
+	Projects int
+	Records  int
+	Tags     int
+	// Conflicts contains the keys of all existing projects, records and tags
+	// that differ from the ones in the archive.
+	Conflicts []string
+	// Config contains the configuration file stored in the archive, if any.
+	// Restoring it is up to the caller.
+	Config []byte
+}
+
+// Export writes all projects, records and registered tags to w as a
+// gzip-compressed tar archive.
+// The archive also contains a manifest and, if it isn't nil, the given config
+// file. Backups of projects and records are not exported.
+func (t *Timetrace) Export(w io.Writer, configFile []byte) (*ArchiveManifest, error) {
+	projectKeys, err := t.fs.ProjectKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	recordKeys, err := t.fs.RecordKeys(time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	tagKeys, err := t.fs.TagKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &ArchiveManifest{
+		Version:  ArchiveVersion,
+		Created:  time.Now(),
+		Projects: len(projectKeys),
+		Records:  len(recordKeys),
+		Tags:     len(tagKeys),
+		Config:   configFile != nil,
+	}
+
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	manifestData, err := json.MarshalIndent(manifest, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeArchiveFile(tarWriter, archiveManifestName, manifestData); err != nil {
+		return nil, err
+	}
+
+	if configFile != nil {
+		if err := writeArchiveFile(tarWriter, archiveConfigName, configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, key := range projectKeys {
+		data, err := t.fs.LoadProject(key)
+		if err != nil {
+			return nil, err
+		}
+
+		name := path.Join(archiveProjectsDir, key+".json")
+		if err := writeArchiveFile(tarWriter, name, data); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, key := range recordKeys {
+		data, err := t.fs.LoadRecord(key)
+		if err != nil {
+			return nil, err
+		}
+
+		name := path.Join(archiveRecordsDir, key.Format(archiveRecordLayout)+".json")
+		if err := writeArchiveFile(tarWriter, name, data); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, key := range tagKeys {
+		data, err := t.fs.LoadTag(key)
+		if err != nil {
+			return nil, err
+		}
+
+		name := path.Join(archiveTagsDir, key+".json")
+		if err := writeArchiveFile(tarWriter, name, data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// Restore reads an archive created by Export and saves all projects, records
+// and tags contained in it. The archive is verified completely before anything is saved.
+//
+// If projects, records or tags in the archive conflict with existing ones that have
+// different contents, nothing is saved and ErrArchiveConflicts is returned
+// along with the summary listing the conflicts. Setting force overwrites the
+// existing projects and records instead.
+func (t *Timetrace) Restore(r io.Reader, force bool) (*RestoreSummary, error) {
+	files, err := readArchiveFiles(r)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &RestoreSummary{
+		Config:    files[archiveConfigName],
+		Conflicts: make([]string, 0),
+	}
+
+	manifestData, ok := files[archiveManifestName]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidArchive, archiveManifestName)
+	}
+
+	if err := json.Unmarshal(manifestData, &summary.Manifest); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+	}
+
+	if summary.Manifest.Version < 1 || summary.Manifest.Version > ArchiveVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedArchive, summary.Manifest.Version)
+	}
+
+	projects, records, tags, err := decodeArchiveFiles(files)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(projects) != summary.Manifest.Projects || len(records) != summary.Manifest.Records || len(tags) != summary.Manifest.Tags {
+		return nil, fmt.Errorf("%w: archive contains %d projects, %d records and %d tags, manifest expects %d, %d and %d",
+			ErrInvalidArchive, len(projects), len(records), len(tags),
+			summary.Manifest.Projects, summary.Manifest.Records, summary.Manifest.Tags)
+	}
+
+	if err := t.verifyArchive(projects, records, tags); err != nil {
+		return nil, err
+	}
+
+	// Determine which projects and records have to be saved and which of them
+	// conflict with existing ones.
+	var changedProjects []*Project
+	var changedRecords []*Record
+	var changedTags []*Tag
+
+	for _, project := range projects {
+		existing, err := t.LoadProject(project.Key)
+		if errors.Is(err, ErrProjectNotFound) {
+			changedProjects = append(changedProjects, project)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if !sameJSON(existing, project) {
+			summary.Conflicts = append(summary.Conflicts, project.Key)
+			changedProjects = append(changedProjects, project)
+		}
+	}
+
+	for _, record := range records {
+		existing, err := t.LoadRecord(record.Start)
+		if errors.Is(err, ErrRecordNotFound) {
+			changedRecords = append(changedRecords, record)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if !sameJSON(existing, record) {
+			summary.Conflicts = append(summary.Conflicts, t.Formatter().RecordKey(record))
+			changedRecords = append(changedRecords, record)
+		}
+	}
+
+	for _, tag := range tags {
+		existing, err := t.LoadTag(tag.Key)
+		if errors.Is(err, ErrTagNotFound) {
+			changedTags = append(changedTags, tag)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if !sameJSON(existing, tag) {
+			summary.Conflicts = append(summary.Conflicts, tag.Key)
+			changedTags = append(changedTags, tag)
+		}
+	}
+
+	if len(summary.Conflicts) > 0 && !force {
+		return summary, ErrArchiveConflicts
+	}
+
+	// Parent projects have to be saved before their modules.
+	sort.SliceStable(changedProjects, func(i, j int) bool {
+		return !changedProjects[i].IsModule() && changedProjects[j].IsModule()
+	})
+
+	for _, project := range changedProjects {
+		if err := t.SaveProject(*project, true); err != nil {
+			return nil, err
+		}
+		summary.Projects++
+	}
+
+	for _, record := range changedRecords {
+		if err := t.SaveRecord(*record, true); err != nil {
+			return nil, err
+		}
+		summary.Records++
+	}
+
+	for _, tag := range changedTags {
+		if err := t.SaveTag(*tag, true); err != nil {
+			return nil, err
+		}
+		summary.Tags++
+	}
+
+	return summary, nil
+}
+
+// verifyArchive checks if the projects, records and tags of an archive are
+// valid. Each project must have a key, modules must have a parent, records need
+// a start time, an end time after the start time and a known project, and tags
+// need a valid key. Records must neither collide with each other nor with
+// existing records other than the one with the same key, which is replaced by
+// the restored record.
+func (t *Timetrace) verifyArchive(projects []*Project, records []*Record, tags []*Tag) error {
+	knownProjects := make(map[string]bool)
+
+	existingProjects, err := t.ListProjects()
+	if err != nil {
+		return err
+	}
+
+	for _, project := range existingProjects {
+		knownProjects[project.Key] = true
+	}
+
+	for _, project := range projects {
+		if project.Key == "" {
+			return fmt.Errorf("%w: project without key", ErrInvalidArchive)
+		}
+		knownProjects[project.Key] = true
+	}
+
+	for _, project := range projects {
+		if project.IsModule() && !knownProjects[project.Parent()] {
+			return fmt.Errorf("%w: module %s has no parent project", ErrInvalidArchive, project.Key)
+		}
+	}
+
+	for _, record := range records {
+		if record.Start.IsZero() {
+			return fmt.Errorf("%w: record without start time", ErrInvalidArchive)
+		}
+
+		key := t.Formatter().RecordKey(record)
+
+		if record.End != nil && record.End.Before(record.Start) {
+			return fmt.Errorf("%w: record %s ends before it starts", ErrInvalidArchive, key)
+		}
+
+		if record.Project == nil || !knownProjects[record.Project.Key] {
+			return fmt.Errorf("%w: record %s has no known project", ErrInvalidArchive, key)
+		}
+
+		if err := t.assertNoCollisions(*record, record.Start); err != nil {
+			return fmt.Errorf("record %s: %w", key, err)
+		}
+	}
+
+	// The records of the archive must not collide with each other either:
+	// Sorted by start time, each non-parallel record has to end before the
+	// next one starts.
+	sorted := make([]*Record, 0, len(records))
+	for _, record := range records {
+		if !record.IsParallel {
+			sorted = append(sorted, record)
+		}
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+
+	for i := 1; i < len(sorted); i++ {
+		previous := sorted[i-1]
+		if previous.End == nil || previous.End.After(sorted[i].Start) {
+			return fmt.Errorf("record %s: %w: %s", t.Formatter().RecordKey(sorted[i]),
+				ErrRecordCollides, t.Formatter().RecordKey(previous))
+		}
+	}
+
+	for _, tag := range tags {
+		if !isValidTag(tag.Key) {
+			return fmt.Errorf("%w: invalid tag %q", ErrInvalidArchive, tag.Key)
+		}
+	}
+
+	return nil
+}
+
+func writeArchiveFile(w *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+
+	if err := w.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err := w.Write(data)
+
+	return err
+}
+
+// readArchiveFiles reads all regular files of a gzip-compressed tar archive and
+// returns their contents by name.
+func readArchiveFiles(r io.Reader) (map[string][]byte, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	files := make(map[string][]byte)
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := ioutil.ReadAll(tarReader)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+		}
+
+		files[path.Clean(header.Name)] = data
+	}
+
+	return files, nil
+}
+
+// decodeArchiveFiles decodes all project, record and tag files of an archive.
+func decodeArchiveFiles(files map[string][]byte) ([]*Project, []*Record, []*Tag, error) {
+	var projects []*Project
+	var records []*Record
+	var tags []*Tag
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		switch path.Dir(name) {
+		case archiveProjectsDir:
+			var project Project
+			if err := decodeStrict(files[name], &project); err != nil {
+				return nil, nil, nil, fmt.Errorf("%w: %s: %s", ErrInvalidArchive, name, err)
+			}
+			projects = append(projects, &project)
+		case archiveRecordsDir:
+			var record Record
+			if err := decodeStrict(files[name], &record); err != nil {
+				return nil, nil, nil, fmt.Errorf("%w: %s: %s", ErrInvalidArchive, name, err)
+			}
+			records = append(records, &record)
+		case archiveTagsDir:
+			var tag Tag
+			if err := decodeStrict(files[name], &tag); err != nil {
+				return nil, nil, nil, fmt.Errorf("%w: %s: %s", ErrInvalidArchive, name, err)
+			}
+			tags = append(tags, &tag)
+		}
+	}
+
+	return projects, records, tags, nil
+}
+
+// decodeStrict decodes JSON data and fails on fields unknown to v, which would
+// get lost when saving v again. The data must consist of a single JSON value,
+// anything following it is rejected.
+func decodeStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+
+	var extra json.RawMessage
+	if decoder.More() || decoder.Decode(&extra) != io.EOF {
+		return errors.New("unexpected data after top-level value")
+	}
+
+	return nil
+}
+
+// sameJSON checks if two values have the same JSON representation.
+func sameJSON(a, b interface{}) bool {
+	dataA, errA := json.Marshal(a)
+	dataB, errB := json.Marshal(b)
+
+	return errA == nil && errB == nil && bytes.Equal(dataA, dataB)
+}
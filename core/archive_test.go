@@ -0,0 +1,167 @@
+package core
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDecodeArchiveFiles(t *testing.T) {
+	tests := map[string]struct {
+		files       map[string]string
+		projects    int
+		records     int
+		tags        int
+		expectedErr error
+	}{
+		"valid archive": {
+			files: map[string]string{
+				"manifest.json":                  `{"version": 1}`,
+				"projects/make-coffee.json":      `{"key": "make-coffee"}`,
+				"records/2021-05-01-08-00.json":  `{"start": "2021-05-01T08:00:00Z", "project": {"key": "make-coffee"}}`,
+				"records/2021-05-01-10-00.json":  `{"start": "2021-05-01T10:00:00Z", "project": {"key": "make-coffee"}}`,
+				"tags/espresso.json":             `{"key": "espresso", "color": "brown"}`,
+				"unrelated/2021-05-01-10-0.json": `{}`,
+			},
+			projects: 1,
+			records:  2,
+			tags:     1,
+		},
+		"unknown field": {
+			files: map[string]string{
+				"manifest.json":             `{"version": 1}`,
+				"projects/make-coffee.json": `{"key": "make-coffee", "unknown": true}`,
+			},
+			expectedErr: ErrInvalidArchive,
+		},
+	}
+
+	for name, tc := range tests {
+		var buf bytes.Buffer
+
+		gzipWriter := gzip.NewWriter(&buf)
+		tarWriter := tar.NewWriter(gzipWriter)
+
+		for fileName, data := range tc.files {
+			if err := writeArchiveFile(tarWriter, fileName, []byte(data)); err != nil {
+				t.Fatalf("%s: unexpected error: %s", name, err)
+			}
+		}
+
+		tarWriter.Close()
+		gzipWriter.Close()
+
+		files, err := readArchiveFiles(&buf)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+
+		if len(files) != len(tc.files) {
+			t.Errorf("%s: expected %d files, got %d", name, len(tc.files), len(files))
+		}
+
+		projects, records, tags, err := decodeArchiveFiles(files)
+		if !errors.Is(err, tc.expectedErr) {
+			t.Fatalf("%s: expected error %v, got %v", name, tc.expectedErr, err)
+		}
+
+		if len(projects) != tc.projects || len(records) != tc.records || len(tags) != tc.tags {
+			t.Errorf("%s: expected %d projects, %d records and %d tags, got %d, %d and %d",
+				name, tc.projects, tc.records, tc.tags, len(projects), len(records), len(tags))
+		}
+	}
+}
+
+func TestVerifyArchiveCollisions(t *testing.T) {
+	at := func(hour, minute int) *time.Time {
+		t := time.Date(2021, 05, 01, hour, minute, 0, 0, time.Local)
+		return &t
+	}
+
+	tests := map[string]struct {
+		record      Record
+		expectedErr error
+	}{
+		"same key": {
+			record: Record{Start: *at(9, 00), End: at(9, 45)},
+		},
+		"after existing record": {
+			record: Record{Start: *at(10, 00), End: at(11, 00)},
+		},
+		"overlapping existing record": {
+			record:      Record{Start: *at(9, 30), End: at(10, 30)},
+			expectedErr: ErrRecordCollides,
+		},
+	}
+
+	for name, tc := range tests {
+		tt := newMemoryTimetrace(t)
+
+		existing := Record{Start: *at(9, 00), End: at(10, 00), Project: &Project{Key: "make-coffee"}}
+		if err := tt.SaveRecord(existing, false); err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+
+		tc.record.Project = &Project{Key: "make-coffee"}
+
+		err := tt.verifyArchive(nil, []*Record{&tc.record}, nil)
+		if !errors.Is(err, tc.expectedErr) {
+			t.Errorf("%s: expected error %v, got %v", name, tc.expectedErr, err)
+		}
+	}
+}
+
+func TestVerifyArchiveOverlappingRecords(t *testing.T) {
+	at := func(hour, minute int) *time.Time {
+		t := time.Date(2021, 05, 01, hour, minute, 0, 0, time.Local)
+		return &t
+	}
+
+	tests := map[string]struct {
+		records     []*Record
+		expectedErr error
+	}{
+		"consecutive records": {
+			records: []*Record{
+				{Start: *at(10, 00), End: at(11, 00)},
+				{Start: *at(9, 00), End: at(10, 00)},
+			},
+		},
+		"overlapping records": {
+			records: []*Record{
+				{Start: *at(10, 00), End: at(11, 00)},
+				{Start: *at(9, 00), End: at(10, 30)},
+			},
+			expectedErr: ErrRecordCollides,
+		},
+		"record after running record": {
+			records: []*Record{
+				{Start: *at(9, 00)},
+				{Start: *at(10, 00), End: at(11, 00)},
+			},
+			expectedErr: ErrRecordCollides,
+		},
+		"overlapping parallel record": {
+			records: []*Record{
+				{Start: *at(9, 00), End: at(10, 30), IsParallel: true},
+				{Start: *at(10, 00), End: at(11, 00)},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		tt := newMemoryTimetrace(t)
+
+		for _, record := range tc.records {
+			record.Project = &Project{Key: "make-coffee"}
+		}
+
+		err := tt.verifyArchive(nil, tc.records, nil)
+		if !errors.Is(err, tc.expectedErr) {
+			t.Errorf("%s: expected error %v, got %v", name, tc.expectedErr, err)
+		}
+	}
+}
@@ -1,51 +1,250 @@
 package core
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // Formatter represents a date- and time formatter. It provides all displayed
 // date- and time layouts and is capable of parsing those layouts.
+//
+// Dates and times are parsed in the home time zone, so that a date always
+// refers to the same day no matter where a record has been created. Times are
+// displayed in the display time zone, which is the home time zone unless it
+// has been changed using SetDisplayLocation.
 type Formatter struct {
 	use12Hours      bool
 	useDecimalHours string
+	location        *time.Location
+	displayLocation *time.Location
+}
+
+// Location returns the home time zone used for parsing dates and times.
+func (f *Formatter) Location() *time.Location {
+	if f.location == nil {
+		return time.Local
+	}
+	return f.location
+}
+
+// SetDisplayLocation changes the time zone times and dates are displayed in.
+func (f *Formatter) SetDisplayLocation(loc *time.Location) {
+	f.displayLocation = loc
+}
+
+func (f *Formatter) displayIn(input time.Time) time.Time {
+	if f.displayLocation == nil {
+		return input.In(f.Location())
+	}
+	return input.In(f.displayLocation)
+}
+
+// Now returns the current time in the home time zone.
+func (f *Formatter) Now() time.Time {
+	return time.Now().In(f.Location())
 }
 
 const dateLayout = "2006-01-02"
 
+var (
+	ErrInvalidDate = errors.New("invalid date, use YYYY-MM-DD, today, yesterday, a weekday like monday or last friday or an offset like -3d or 2w ago")
+	ErrInvalidTime = errors.New("invalid time, use a time like 17:30 or 5:30PM, optionally preceded by a date, or an offset like 10 min ago")
+)
+
+var (
+	// dateOffsetPattern matches offsets of whole days, weeks or months.
+	dateOffsetPattern = regexp.MustCompile(`^(\d+)\s*(d|days?|w|weeks?|months?)$`)
+	// clockOffsetPattern matches a single component of an offset like 1h 30min.
+	clockOffsetPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*(hours?|hrs?|h|minutes?|mins?|m|seconds?|secs?|s)`)
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
 // ParseDate parses a date from an input string in the form YYYY-MM-DD. It also
-// supports the `today` and `yesterday` aliases for convenience.
+// supports the `today` and `yesterday` aliases, weekdays like `monday` for the
+// most recent Monday, `last friday` for the Friday before today and offsets
+// like `-3d` or `2w ago`. The returned date is midnight in the home time zone.
 func (f *Formatter) ParseDate(input string) (time.Time, error) {
-	if input == "today" {
-		return time.Now(), nil
+	input = strings.ToLower(strings.TrimSpace(input))
+	today := f.today()
+
+	switch input {
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+
+	if date, ok := parseWeekday(input, today); ok {
+		return date, nil
 	}
-	if input == "yesterday" {
-		yesterday := time.Now().AddDate(0, 0, -1)
-		return yesterday, nil
+
+	if offset, ok := relativeOffset(input); ok {
+		if date, ok := subtractDateOffset(today, offset); ok {
+			return date, nil
+		}
 	}
 
-	date, err := time.Parse(dateLayout, input)
+	date, err := time.ParseInLocation(dateLayout, input, f.Location())
 	if err != nil {
-		return time.Time{}, err
+		return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidDate, input)
 	}
 	return date, nil
 }
 
-// ParseTime parses a time from an input string in the configured timeLayout
+// ParseTime parses a time of day from an input string. Both the 24-hour clock
+// like 17:30 and the 12-hour clock like 5:30PM or 5pm are accepted.
 func (f *Formatter) ParseTime(input string) (time.Time, error) {
-	date, err := time.Parse(f.timeLayout(), input)
+	normalized := strings.ToUpper(strings.ReplaceAll(input, " ", ""))
+
+	for _, layout := range []string{defaultTimeLayout, "3:04PM", "3PM"} {
+		if date, err := time.ParseInLocation(layout, normalized, f.Location()); err == nil {
+			return date, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidTime, input)
+}
+
+// ParseDateTime parses a point in time from an input string. It accepts `now`,
+// offsets like `10 min ago`, `-1h30m` or `2d ago`, a time of day like 17:30 for
+// today or a date as understood by ParseDate followed by a time of day, like
+// `yesterday 17:30` or `last friday 9am`.
+func (f *Formatter) ParseDateTime(input string) (time.Time, error) {
+	normalized := strings.ToLower(strings.TrimSpace(input))
+	now := f.Now()
+
+	if normalized == "now" {
+		return now, nil
+	}
+
+	if offset, ok := relativeOffset(normalized); ok {
+		if date, ok := subtractDateOffset(now, offset); ok {
+			return date, nil
+		}
+		if duration, ok := parseClockOffset(offset); ok {
+			return now.Add(-duration), nil
+		}
+		return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidTime, input)
+	}
+
+	if clock, err := f.ParseTime(normalized); err == nil {
+		return f.CombineDateAndTime(now, clock), nil
+	}
+
+	// The date may contain spaces itself, e.g. "last friday", and so may the
+	// time, e.g. "9 am". Therefore, all possible splits are tried.
+	for i, char := range normalized {
+		if char != ' ' {
+			continue
+		}
+		date, err := f.ParseDate(normalized[:i])
+		if err != nil {
+			continue
+		}
+		clock, err := f.ParseTime(normalized[i+1:])
+		if err != nil {
+			continue
+		}
+		return f.CombineDateAndTime(date, clock), nil
+	}
+
+	return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidTime, input)
+}
+
+// today returns midnight of the current day in the home time zone.
+func (f *Formatter) today() time.Time {
+	year, month, day := f.Now().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, f.Location())
+}
+
+// parseWeekday parses weekdays like `monday` or `last monday`. A plain weekday
+// is the most recent such day, which may be today. `last` refers to the most
+// recent such day before today.
+func parseWeekday(input string, today time.Time) (time.Time, bool) {
+	last := strings.HasPrefix(input, "last ")
+	if last {
+		input = strings.TrimSpace(strings.TrimPrefix(input, "last "))
+	}
+
+	weekday, ok := weekdays[input]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	days := (int(today.Weekday()) - int(weekday) + 7) % 7
+	if days == 0 && last {
+		days = 7
+	}
+
+	return today.AddDate(0, 0, -days), true
+}
+
+// relativeOffset returns the offset of an input like `-3d` or `3d ago`. If the
+// input isn't relative, false is returned.
+func relativeOffset(input string) (string, bool) {
+	if strings.HasPrefix(input, "-") {
+		return strings.TrimSpace(strings.TrimPrefix(input, "-")), true
+	}
+	if strings.HasSuffix(input, "ago") {
+		return strings.TrimSpace(strings.TrimSuffix(input, "ago")), true
+	}
+	return "", false
+}
+
+// subtractDateOffset subtracts an offset of days, weeks or months like `3d` or
+// `2 weeks` from the given time.
+func subtractDateOffset(t time.Time, offset string) (time.Time, bool) {
+	match := dateOffsetPattern.FindStringSubmatch(offset)
+	if match == nil {
+		return time.Time{}, false
+	}
+
+	n, err := strconv.Atoi(match[1])
 	if err != nil {
-		return time.Time{}, err
+		return time.Time{}, false
 	}
-	return date, nil
+
+	switch match[2][0] {
+	case 'w':
+		return t.AddDate(0, 0, -7*n), true
+	case 'm':
+		return t.AddDate(0, -n, 0), true
+	default:
+		return t.AddDate(0, 0, -n), true
+	}
+}
+
+// parseClockOffset parses an offset like `10 min`, `1h 30m` or `1.5 hours`.
+func parseClockOffset(offset string) (time.Duration, bool) {
+	normalized := clockOffsetPattern.ReplaceAllStringFunc(offset, func(component string) string {
+		match := clockOffsetPattern.FindStringSubmatch(component)
+		return match[1] + match[2][:1]
+	})
+
+	duration, err := time.ParseDuration(strings.ReplaceAll(normalized, " ", ""))
+	if err != nil || duration < 0 {
+		return 0, false
+	}
+
+	return duration, true
 }
 
 // CombineDateAndTime takes a date and a time and combines them to the time
-// struct that represents the given time on the given day
+// struct that represents the given time on the given day in the home time zone.
 func (f *Formatter) CombineDateAndTime(d, t time.Time) time.Time {
-	year, month, day := d.Date()
-	return time.Date(year, month, day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local)
+	year, month, day := d.In(f.Location()).Date()
+	return time.Date(year, month, day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), f.Location())
 }
 
 const (
@@ -62,13 +261,18 @@ func (f *Formatter) timeLayout() string {
 }
 
 func (f *Formatter) TimeString(input time.Time) string {
-	return input.Format(f.timeLayout())
+	return f.displayIn(input).Format(f.timeLayout())
 }
 
 // PrettyDateString returns a nice representation of a given time
 // example: Mon 31. May 2021
 func (f *Formatter) PrettyDateString(input time.Time) string {
-	return input.Format(defaultDatelayoutPretty)
+	return f.displayIn(input).Format(defaultDatelayoutPretty)
+}
+
+// DateString returns the date of the given time in the form 2006-01-02.
+func (f *Formatter) DateString(input time.Time) string {
+	return f.displayIn(input).Format(dateLayout)
 }
 
 const (
@@ -84,13 +288,14 @@ func (f *Formatter) RecordKeyLayout() string {
 }
 
 // ParseRecordKey parses an input string in the form 2006-01-02-15-04 or
-// 2006-01-02-03-04PM depending on the use12hours setting.
+// 2006-01-02-03-04PM depending on the use12hours setting. Record keys always
+// refer to the home time zone.
 func (f *Formatter) ParseRecordKey(key string) (time.Time, error) {
-	return time.Parse(f.RecordKeyLayout(), key)
+	return time.ParseInLocation(f.RecordKeyLayout(), key, f.Location())
 }
 
 func (f *Formatter) RecordKey(record *Record) string {
-	return record.Start.Format(f.RecordKeyLayout())
+	return record.Start.In(f.Location()).Format(f.RecordKeyLayout())
 }
 
 // formatDuration formats the passed duration into a string.
@@ -116,6 +321,38 @@ func (f *Formatter) FormatDuration(duration time.Duration) string {
 	return response
 }
 
+// FormatCsvDuration formats the passed duration for machine-readable output
+// like CSV files. If UseDecimalHours is "On" or "Both", the duration will be a
+// plain decimal number of hours like "8.40". Otherwise, it will be formatted
+// by FormatDuration.
+func (f *Formatter) FormatCsvDuration(duration time.Duration) string {
+	switch f.useDecimalHours {
+	case "On", "Both":
+		return fmt.Sprintf("%.2f", duration.Minutes()/60)
+	default:
+		return f.FormatDuration(duration)
+	}
+}
+
+// FormatAmounts formats billable amounts keyed by their currency, e.g. as
+// "120.00 EUR, 45.50 USD". Amounts without a currency are printed as a plain
+// number. If there are no amounts, an empty string is returned.
+func (f *Formatter) FormatAmounts(amounts map[string]float64) string {
+	currencies := make([]string, 0, len(amounts))
+	for currency := range amounts {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	formatted := make([]string, 0, len(currencies))
+	for _, currency := range currencies {
+		amount := strings.TrimSpace(fmt.Sprintf("%.2f %s", amounts[currency], currency))
+		formatted = append(formatted, amount)
+	}
+
+	return strings.Join(formatted, ", ")
+}
+
 func (f *Formatter) FormatTags(tags []string) string {
 	var result string
 
@@ -1,6 +1,10 @@
 package core
 
-import "testing"
+import (
+	"errors"
+	"testing"
+	"time"
+)
 
 func TestFormatter_FormatTags(t *testing.T) {
 	tests := map[string]struct {
@@ -30,3 +34,157 @@ func TestFormatter_FormatTags(t *testing.T) {
 		}
 	}
 }
+
+func TestFormatter_Location(t *testing.T) {
+	home := time.FixedZone("home", 2*60*60)
+	display := time.FixedZone("display", -5*60*60)
+
+	formatter := Formatter{location: home}
+
+	date, err := formatter.ParseDate("2021-06-07")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected := time.Date(2021, 06, 07, 0, 00, 00, 00, home); !date.Equal(expected) {
+		t.Errorf("parse date: expected %s, got %s", expected, date)
+	}
+
+	key, err := formatter.ParseRecordKey("2021-06-07-23-30")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected := time.Date(2021, 06, 07, 23, 30, 00, 00, home); !key.Equal(expected) {
+		t.Errorf("parse record key: expected %s, got %s", expected, key)
+	}
+
+	// The record has been started in UTC, but is keyed in the home time zone.
+	record := &Record{Start: time.Date(2021, 06, 07, 22, 30, 00, 00, time.UTC)}
+	if key := formatter.RecordKey(record); key != "2021-06-08-00-30" {
+		t.Errorf("record key: expected 2021-06-08-00-30, got %s", key)
+	}
+
+	clock, err := formatter.ParseTime("09:00")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	combined := formatter.CombineDateAndTime(record.Start, clock)
+	if expected := time.Date(2021, 06, 8, 9, 00, 00, 00, home); !combined.Equal(expected) {
+		t.Errorf("combine date and time: expected %s, got %s", expected, combined)
+	}
+
+	if start := formatter.TimeString(record.Start); start != "00:30" {
+		t.Errorf("time string: expected 00:30, got %s", start)
+	}
+	if date := formatter.DateString(record.Start); date != "2021-06-08" {
+		t.Errorf("date string: expected 2021-06-08, got %s", date)
+	}
+
+	formatter.SetDisplayLocation(display)
+
+	if start := formatter.TimeString(record.Start); start != "17:30" {
+		t.Errorf("time string in display location: expected 17:30, got %s", start)
+	}
+	if date := formatter.DateString(record.Start); date != "2021-06-07" {
+		t.Errorf("date string in display location: expected 2021-06-07, got %s", date)
+	}
+}
+
+func TestFormatter_ParseDate(t *testing.T) {
+	formatter := Formatter{location: time.UTC}
+	today := formatter.today()
+
+	tests := map[string]struct {
+		input       string
+		expected    time.Time
+		expectedErr bool
+	}{
+		"date":          {input: "2021-06-07", expected: time.Date(2021, 06, 07, 0, 00, 00, 00, time.UTC)},
+		"today":         {input: "today", expected: today},
+		"yesterday":     {input: "Yesterday", expected: today.AddDate(0, 0, -1)},
+		"days offset":   {input: "-3d", expected: today.AddDate(0, 0, -3)},
+		"weeks ago":     {input: "2w ago", expected: today.AddDate(0, 0, -14)},
+		"months ago":    {input: "1 month ago", expected: today.AddDate(0, -1, 0)},
+		"invalid":       {input: "someday", expectedErr: true},
+		"invalid unit":  {input: "3 lightyears ago", expectedErr: true},
+		"invalid month": {input: "2021-13-01", expectedErr: true},
+	}
+
+	for name, tc := range tests {
+		date, err := formatter.ParseDate(tc.input)
+		if tc.expectedErr {
+			if !errors.Is(err, ErrInvalidDate) {
+				t.Errorf("%s: expected ErrInvalidDate, got %v", name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+		if !date.Equal(tc.expected) {
+			t.Errorf("%s: expected %s, got %s", name, tc.expected, date)
+		}
+	}
+
+	for input, last := range map[string]bool{"monday": false, "last monday": true} {
+		date, err := formatter.ParseDate(input)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", input, err)
+		}
+
+		days := int(today.Sub(date).Hours() / 24)
+		if date.Weekday() != time.Monday || days > 7 || days < 0 || (days == 0 && last) || (days == 7 && !last) {
+			t.Errorf("%s: got %s for today %s", input, date, today)
+		}
+	}
+}
+
+func TestFormatter_ParseDateTime(t *testing.T) {
+	formatter := Formatter{location: time.UTC}
+	today := formatter.today()
+
+	tests := map[string]struct {
+		input       string
+		expected    time.Time
+		expectedErr bool
+	}{
+		"time":              {input: "17:30", expected: today.Add(17*time.Hour + 30*time.Minute)},
+		"12-hour time":      {input: "5:30 pm", expected: today.Add(17*time.Hour + 30*time.Minute)},
+		"date and time":     {input: "2021-06-07 9am", expected: time.Date(2021, 06, 07, 9, 00, 00, 00, time.UTC)},
+		"yesterday":         {input: "yesterday 17:30", expected: today.AddDate(0, 0, -1).Add(17*time.Hour + 30*time.Minute)},
+		"date without time": {input: "yesterday", expectedErr: true},
+		"invalid offset":    {input: "10 lightyears ago", expectedErr: true},
+	}
+
+	for name, tc := range tests {
+		date, err := formatter.ParseDateTime(tc.input)
+		if tc.expectedErr {
+			if !errors.Is(err, ErrInvalidTime) {
+				t.Errorf("%s: expected ErrInvalidTime, got %v", name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+		if !date.Equal(tc.expected) {
+			t.Errorf("%s: expected %s, got %s", name, tc.expected, date)
+		}
+	}
+
+	for input, offset := range map[string]time.Duration{
+		"10 min ago":  10 * time.Minute,
+		"-1h30m":      90 * time.Minute,
+		"2 hours ago": 2 * time.Hour,
+		"1d ago":      24 * time.Hour,
+	} {
+		before := time.Now()
+		date, err := formatter.ParseDateTime(input)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", input, err)
+		}
+		after := time.Now()
+		if date.Before(before.Add(-offset)) || date.After(after.Add(-offset)) {
+			t.Errorf("%s: expected %s ago, got %s ago", input, offset, after.Sub(date))
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package core
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Supported formats for importing records from other time trackers.
+const (
+	ImportFormatTogglCsv    = "toggl-csv"
+	ImportFormatWatson      = "watson"
+	ImportFormatTimewarrior = "timewarrior"
+)
+
+// defaultImportProject is the project key used for imported records that don't
+// belong to any project.
+const defaultImportProject = "unassigned"
+
+const (
+	togglDateTimeLayout = "2006-01-02 15:04:05"
+	timewDateTimeLayout = "20060102T150405Z"
+)
+
+var (
+	ErrUnknownImportFormat = errors.New("unknown import format")
+)
+
+// ImportSummary describes the outcome of an import.
+type ImportSummary struct {
+	// Imported contains all records that have been imported.
+	Imported []*Record
+	// Colliding contains all records that haven't been imported because they
+	// collide with an existing or another imported record.
+	Colliding []*Record
+	// Skipped contains all records that haven't been imported because they
+	// don't have an end time.
+	Skipped []*Record
+	// Rejected contains all records that haven't been imported because they
+	// have invalid tags or, if strict tags are enabled, unregistered tags.
+	Rejected []*Record
+	// NewProjects contains the keys of all projects that have been created.
+	NewProjects []string
+}
+
+// ParseImport parses the records exported by another time tracker. The format
+// must be one of the ImportFormat* constants:
+//
+//   - toggl-csv: A CSV file exported from the Toggl detailed report.
+//   - watson: The output of `watson log --json` or Watson's frames file.
+//   - timewarrior: The output of `timew export`. The first tag of each
+//     interval is used as project key, the remaining tags are used as tags.
+//
+// Times are converted to the given location, and times without a time zone
+// such as Toggl's are read in that location.
+//
+// Project names are converted to project keys by lowercasing them and replacing
+// whitespace with dashes. Toggl tasks are imported as project modules. Tags are
+// converted the same way but keep their case, and a leading ! is removed.
+func ParseImport(format string, r io.Reader, loc *time.Location) ([]Record, error) {
+	switch format {
+	case ImportFormatTogglCsv:
+		return parseTogglCsv(r, loc)
+	case ImportFormatWatson:
+		return parseWatson(r, loc)
+	case ImportFormatTimewarrior:
+		return parseTimewarrior(r, loc)
+	default:
+		return nil, ErrUnknownImportFormat
+	}
+}
+
+// Import saves the given records and creates all projects and modules that
+// don't exist yet. Records that collide with existing records or with another
+// imported record aren't imported. Neither are records without an end time or
+// with tags that don't pass ValidateTags.
+//
+// All records are checked before anything is saved, so that a colliding record
+// can't leave the store with a partial import.
+//
+// If dryRun is set, nothing will be saved but the returned summary still shows
+// what would have been imported.
+func (t *Timetrace) Import(records []Record, dryRun bool) (*ImportSummary, error) {
+	summary := &ImportSummary{
+		Imported:    make([]*Record, 0),
+		Colliding:   make([]*Record, 0),
+		Skipped:     make([]*Record, 0),
+		Rejected:    make([]*Record, 0),
+		NewProjects: make([]string, 0),
+	}
+
+	projects, err := t.ListProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	knownProjects := make(map[string]bool)
+	for _, project := range projects {
+		knownProjects[project.Key] = true
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Start.Before(records[j].Start)
+	})
+
+	for i := range records {
+		record := &records[i]
+
+		if record.End == nil {
+			summary.Skipped = append(summary.Skipped, record)
+			continue
+		}
+
+		if err := t.ValidateTags(record.Tags); errors.Is(err, ErrInvalidTag) || errors.Is(err, ErrUnknownTag) {
+			summary.Rejected = append(summary.Rejected, record)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		collide, _, err := t.recordCollides(*record)
+		if err != nil {
+			return nil, err
+		}
+
+		// Records are stored by their start minute, so a record starting
+		// within the same minute as an existing record can't be saved even
+		// if both don't overlap.
+		if _, err := t.fs.LoadRecord(record.Start); err == nil {
+			collide = true
+		}
+
+		if collide || collidesWithImported(*record, summary.Imported) {
+			summary.Colliding = append(summary.Colliding, record)
+			continue
+		}
+
+		// Create the parent project before the module, since modules can't
+		// be created without their parent.
+		keys := []string{record.Project.Key}
+		if record.Project.IsModule() {
+			keys = []string{record.Project.Parent(), record.Project.Key}
+		}
+
+		for _, key := range keys {
+			if knownProjects[key] {
+				continue
+			}
+			knownProjects[key] = true
+			summary.NewProjects = append(summary.NewProjects, key)
+		}
+
+		summary.Imported = append(summary.Imported, record)
+	}
+
+	if dryRun {
+		return summary, nil
+	}
+
+	for _, key := range summary.NewProjects {
+		if err := t.SaveProject(Project{Key: key}, false); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, record := range summary.Imported {
+		if err := t.SaveRecord(*record, false); err != nil {
+			return nil, err
+		}
+	}
+
+	return summary, nil
+}
+
+// collidesWithImported checks if the given record collides with any of the
+// records imported so far. Since records are identified by their start time
+// with minute precision, records starting within the same minute collide too.
+func collidesWithImported(toCheck Record, imported []*Record) bool {
+	if collide, _ := collides(toCheck, imported); collide {
+		return true
+	}
+
+	for _, record := range imported {
+		if record.Start.Truncate(time.Minute).Equal(toCheck.Start.Truncate(time.Minute)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseTogglCsv(r io.Reader, loc *time.Location) ([]Record, error) {
+	reader := csv.NewReader(r)
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("toggl export is empty")
+	}
+
+	// Toggl may add a byte order mark to the first column name.
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[strings.TrimPrefix(strings.TrimSpace(name), "\ufeff")] = i
+	}
+
+	for _, name := range []string{"Project", "Start date", "Start time", "End date", "End time"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("toggl export has no column %s", name)
+		}
+	}
+
+	value := func(row []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	records := make([]Record, 0, len(rows)-1)
+
+	for _, row := range rows[1:] {
+		start, err := time.ParseInLocation(togglDateTimeLayout, value(row, "Start date")+" "+value(row, "Start time"), loc)
+		if err != nil {
+			return nil, err
+		}
+
+		end, err := time.ParseInLocation(togglDateTimeLayout, value(row, "End date")+" "+value(row, "End time"), loc)
+		if err != nil {
+			return nil, err
+		}
+
+		projectKey := importKey(value(row, "Project"))
+		if task := importKey(value(row, "Task")); task != "" && projectKey != "" {
+			projectKey = task + "@" + projectKey
+		}
+
+		tags := importTags(strings.Split(value(row, "Tags"), ","))
+
+		records = append(records, Record{
+			Start:      start,
+			End:        &end,
+			Project:    importProject(projectKey),
+			IsBillable: strings.EqualFold(value(row, "Billable"), "yes"),
+			Tags:       tags,
+			Note:       value(row, "Description"),
+		})
+	}
+
+	return records, nil
+}
+
+// watsonFrame represents a frame printed by `watson log --json`.
+type watsonFrame struct {
+	Project string    `json:"project"`
+	Start   time.Time `json:"start"`
+	Stop    time.Time `json:"stop"`
+	Tags    []string  `json:"tags"`
+}
+
+func parseWatson(r io.Reader, loc *time.Location) ([]Record, error) {
+	var items []json.RawMessage
+
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, err
+	}
+
+	records := make([]Record, 0, len(items))
+
+	for _, item := range items {
+		var frame watsonFrame
+
+		// Watson's frames file stores each frame as an array in the form
+		// [start, stop, project, id, tags, updated_at] with Unix timestamps.
+		if strings.HasPrefix(strings.TrimSpace(string(item)), "[") {
+			var fields []json.RawMessage
+			if err := json.Unmarshal(item, &fields); err != nil {
+				return nil, err
+			}
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("invalid watson frame: %s", string(item))
+			}
+
+			var start, stop int64
+			if err := json.Unmarshal(fields[0], &start); err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(fields[1], &stop); err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(fields[2], &frame.Project); err != nil {
+				return nil, err
+			}
+			if len(fields) > 4 {
+				if err := json.Unmarshal(fields[4], &frame.Tags); err != nil {
+					return nil, err
+				}
+			}
+
+			frame.Start = time.Unix(start, 0)
+			frame.Stop = time.Unix(stop, 0)
+		} else if err := json.Unmarshal(item, &frame); err != nil {
+			return nil, err
+		}
+
+		end := frame.Stop.In(loc)
+
+		records = append(records, Record{
+			Start:   frame.Start.In(loc),
+			End:     &end,
+			Project: importProject(importKey(frame.Project)),
+			Tags:    importTags(frame.Tags),
+		})
+	}
+
+	return records, nil
+}
+
+// timewInterval represents an interval printed by `timew export`.
+type timewInterval struct {
+	Start      string   `json:"start"`
+	End        string   `json:"end"`
+	Tags       []string `json:"tags"`
+	Annotation string   `json:"annotation"`
+}
+
+func parseTimewarrior(r io.Reader, loc *time.Location) ([]Record, error) {
+	var intervals []timewInterval
+
+	if err := json.NewDecoder(r).Decode(&intervals); err != nil {
+		return nil, err
+	}
+
+	records := make([]Record, 0, len(intervals))
+
+	for _, interval := range intervals {
+		start, err := time.Parse(timewDateTimeLayout, interval.Start)
+		if err != nil {
+			return nil, err
+		}
+
+		record := Record{
+			Start: start.In(loc),
+			Note:  interval.Annotation,
+		}
+
+		// An interval without end time is still being tracked.
+		if interval.End != "" {
+			end, err := time.Parse(timewDateTimeLayout, interval.End)
+			if err != nil {
+				return nil, err
+			}
+			end = end.In(loc)
+			record.End = &end
+		}
+
+		var projectKey string
+		if len(interval.Tags) > 0 {
+			projectKey = importKey(interval.Tags[0])
+			record.Tags = importTags(interval.Tags[1:])
+		}
+		record.Project = importProject(projectKey)
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// importKey converts a project name used by another time tracker into a
+// project key by lowercasing it and replacing whitespace with dashes.
+func importKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), "-"))
+}
+
+// importTags converts the tags used by another time tracker into tags that can
+// be used with timetrace by replacing whitespace and commas with dashes and
+// removing the negation prefix. Empty tags are dropped.
+func importTags(tags []string) []string {
+	var converted []string
+
+	for _, tag := range tags {
+		tag = strings.Join(strings.Fields(strings.ReplaceAll(tag, ",", " ")), "-")
+		tag = strings.TrimLeft(tag, tagNegationPrefix)
+
+		if tag != "" {
+			converted = append(converted, tag)
+		}
+	}
+
+	return converted
+}
+
+// importProject returns the project for the given key, falling back to the
+// default import project if the key is empty.
+func importProject(key string) *Project {
+	if key == "" {
+		key = defaultImportProject
+	}
+
+	return &Project{Key: key}
+}
@@ -0,0 +1,151 @@
+package core
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseImport(t *testing.T) {
+	// Use a home time zone that differs from the local one.
+	loc := time.FixedZone("UTC+13", 13*60*60)
+	start := time.Date(2021, 05, 01, 8, 00, 00, 00, loc)
+	end := time.Date(2021, 05, 01, 9, 30, 00, 00, loc)
+
+	tests := map[string]struct {
+		format   string
+		input    string
+		expected Record
+	}{
+		"toggl-csv": {
+			format: ImportFormatTogglCsv,
+			input: "User,Email,Client,Project,Task,Description,Billable,Start date,Start time,End date,End time,Duration,Tags\n" +
+				"Jane,jane@example.com,,Make Coffee,Grind Beans,Espresso,Yes,2021-05-01,08:00:00,2021-05-01,09:30:00,01:30:00,\"morning, dark roast, !late\"\n",
+			expected: Record{
+				Start:      start,
+				End:        &end,
+				Project:    &Project{Key: "grind-beans@make-coffee"},
+				IsBillable: true,
+				Tags:       []string{"morning", "dark-roast", "late"},
+				Note:       "Espresso",
+			},
+		},
+		"watson log": {
+			format: ImportFormatWatson,
+			input: `[{"id": "abc", "project": "make-coffee", "tags": ["morning"], ` +
+				`"start": "` + start.Format(time.RFC3339) + `", "stop": "` + end.Format(time.RFC3339) + `"}]`,
+			expected: Record{
+				Start:   start,
+				End:     &end,
+				Project: &Project{Key: "make-coffee"},
+				Tags:    []string{"morning"},
+			},
+		},
+		"watson frames": {
+			format: ImportFormatWatson,
+			input: `[[` + strings.Join([]string{
+				strconv.FormatInt(start.Unix(), 10), strconv.FormatInt(end.Unix(), 10), `"make-coffee"`, `"abc"`, `["morning"]`, strconv.FormatInt(end.Unix(), 10),
+			}, ", ") + `]]`,
+			expected: Record{
+				Start:   start,
+				End:     &end,
+				Project: &Project{Key: "make-coffee"},
+				Tags:    []string{"morning"},
+			},
+		},
+		"timewarrior": {
+			format: ImportFormatTimewarrior,
+			input: `[{"id": 1, "start": "` + start.UTC().Format(timewDateTimeLayout) + `", ` +
+				`"end": "` + end.UTC().Format(timewDateTimeLayout) + `", "tags": ["make-coffee", "morning"], "annotation": "Espresso"}]`,
+			expected: Record{
+				Start:   start,
+				End:     &end,
+				Project: &Project{Key: "make-coffee"},
+				Tags:    []string{"morning"},
+				Note:    "Espresso",
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		records, err := ParseImport(tc.format, strings.NewReader(tc.input), loc)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+
+		if len(records) != 1 {
+			t.Fatalf("%s: expected 1 record, got %d", name, len(records))
+		}
+
+		record := records[0]
+
+		if !record.Start.Equal(tc.expected.Start) || !record.End.Equal(*tc.expected.End) ||
+			record.Start.Location() != loc || record.End.Location() != loc {
+			t.Errorf("%s: expected %v - %v, got %v - %v", name, tc.expected.Start, tc.expected.End, record.Start, record.End)
+		}
+
+		record.Start, record.End = tc.expected.Start, tc.expected.End
+
+		if !reflect.DeepEqual(record, tc.expected) {
+			t.Errorf("%s: expected %+v, got %+v", name, tc.expected, record)
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	tt := newMemoryTimetrace(t)
+
+	// The existing record doesn't overlap with the second imported record, but
+	// both start within the same minute.
+	existingStart := time.Date(2021, 05, 01, 10, 00, 10, 00, time.Local)
+	existingEnd := existingStart.Add(30 * time.Second)
+	existing := Record{Start: existingStart, End: &existingEnd, Project: &Project{Key: "make-coffee"}}
+
+	if err := tt.SaveRecord(existing, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	newRecord := func(hour, minute, second int, projectKey string) Record {
+		start := time.Date(2021, 05, 01, hour, minute, second, 00, time.Local)
+		end := start.Add(20 * time.Minute)
+		return Record{Start: start, End: &end, Project: &Project{Key: projectKey}}
+	}
+
+	records := []Record{
+		newRecord(9, 00, 00, "make-coffee"),
+		newRecord(10, 00, 50, "make-coffee"),
+		newRecord(11, 00, 00, "grind-beans@clean-kitchen"),
+		newRecord(12, 00, 00, "make-coffee"),
+	}
+	records[3].Tags = []string{"dark roast"}
+
+	summary, err := tt.Import(records, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(summary.Imported) != 2 || len(summary.Colliding) != 1 || len(summary.Rejected) != 1 {
+		t.Fatalf("expected 2 imported, 1 colliding and 1 rejected record, got %d, %d and %d",
+			len(summary.Imported), len(summary.Colliding), len(summary.Rejected))
+	}
+
+	if !reflect.DeepEqual(summary.NewProjects, []string{"clean-kitchen", "grind-beans@clean-kitchen"}) {
+		t.Errorf("unexpected new projects %v", summary.NewProjects)
+	}
+
+	for _, record := range summary.Imported {
+		if _, err := tt.LoadRecord(record.Start); err != nil {
+			t.Errorf("%s: expected the record to be imported, got %s", record.Start, err)
+		}
+	}
+
+	loaded, err := tt.LoadRecord(existingStart)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !loaded.End.Equal(existingEnd) {
+		t.Errorf("expected the existing record to be unchanged, got %+v", loaded)
+	}
+}
@@ -0,0 +1,383 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"sort"
+	"text/template"
+	"time"
+)
+
+// Supported formats for rendering invoices.
+const (
+	InvoiceFormatMarkdown = "markdown"
+	InvoiceFormatHtml     = "html"
+)
+
+// Supported groupings for the line items of an invoice.
+const (
+	InvoiceGroupByDay = "day"
+	InvoiceGroupByTag = "tag"
+)
+
+// untaggedItem is the description of the line item containing all records
+// without tags when grouping by tag.
+const untaggedItem = "untagged"
+
+var (
+	ErrNoBillableRecords      = errors.New("no billable records that haven't been invoiced yet")
+	ErrNoProjectRate          = errors.New("no hourly rate configured for project")
+	ErrMixedCurrencies        = errors.New("records are billed in different currencies")
+	ErrUnknownInvoiceFormat   = errors.New("unknown invoice format")
+	ErrUnknownInvoiceGrouping = errors.New("unknown invoice grouping")
+	ErrInvoiceNumberUsed      = errors.New("invoice number has already been used")
+)
+
+// Client contains the details of the client a project is billed to.
+type Client struct {
+	Name    string `json:"name"`
+	Address string `json:"address,omitempty"`
+	Email   string `json:"email,omitempty"`
+}
+
+// Invoice lists the billable records of a project within a time range as line
+// items. It is rendered using RenderInvoice.
+type Invoice struct {
+	Number   string
+	Date     time.Time
+	Project  *Project
+	Client   *Client
+	From     time.Time
+	To       time.Time
+	Rate     float64
+	Currency string
+	Items    []InvoiceItem
+	// TotalDuration and TotalAmount are the sums of all line items.
+	TotalDuration time.Duration
+	TotalAmount   float64
+	// Records contains all records included in the invoice.
+	Records []*Record
+}
+
+// InvoiceItem is a line item of an invoice, representing either the records of
+// one day or the records with the same tags.
+type InvoiceItem struct {
+	Description string
+	Duration    time.Duration
+	Amount      float64
+}
+
+// FilterNotInvoiced returns true if the record hasn't been included in an
+// issued invoice yet.
+func FilterNotInvoiced(r *Record) bool {
+	return r.Invoice == ""
+}
+
+// DefaultInvoiceNumber returns the number <PROJECT>-<YYYYMMDD> for an invoice
+// of the given project created today in the home time zone. If records have
+// already been invoiced with that number, a suffix like -2 is appended.
+func (t *Timetrace) DefaultInvoiceNumber(projectKey string) (string, error) {
+	usedNumbers, err := t.invoiceNumbers()
+	if err != nil {
+		return "", err
+	}
+
+	number := fmt.Sprintf("%s-%s", projectKey, t.formatter.Now().Format("20060102"))
+
+	for i := 2; usedNumbers[number]; i++ {
+		number = fmt.Sprintf("%s-%s-%d", projectKey, t.formatter.Now().Format("20060102"), i)
+	}
+
+	return number, nil
+}
+
+// invoiceNumbers returns the numbers of all invoices records have been
+// invoiced with.
+func (t *Timetrace) invoiceNumbers() (map[string]bool, error) {
+	records, err := t.loadRecords(time.Time{}, time.Time{}, func(r *Record) bool {
+		return r.Invoice != ""
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	numbers := make(map[string]bool)
+	for _, record := range records {
+		numbers[record.Invoice] = true
+	}
+
+	return numbers, nil
+}
+
+// CreateInvoice creates an invoice with the given number for all billable and
+// not yet invoiced records of a project within the given time range. Records
+// of project modules are included as well. The time range works just like the
+// one of FilterByTimeRange.
+//
+// The amount of each record is computed using the rate returned by ProjectRate,
+// so modules may be billed at a different rate than their parent project. All
+// records have to be billed in the same currency though. If records have already
+// been invoiced with the given number, ErrInvoiceNumberUsed is returned.
+func (t *Timetrace) CreateInvoice(number, projectKey string, from, to time.Time, groupBy string) (*Invoice, error) {
+	if groupBy != InvoiceGroupByDay && groupBy != InvoiceGroupByTag {
+		return nil, ErrUnknownInvoiceGrouping
+	}
+
+	project, err := t.LoadProject(projectKey)
+	if err != nil {
+		return nil, err
+	}
+
+	usedNumbers, err := t.invoiceNumbers()
+	if err != nil {
+		return nil, err
+	}
+
+	if usedNumbers[number] {
+		return nil, fmt.Errorf("%w: %s", ErrInvoiceNumberUsed, number)
+	}
+
+	reporter, err := t.Report(from, to,
+		FilterNoneNilEndTime,
+		FilterBillable(true),
+		FilterNotInvoiced,
+		FilterByProject(projectKey),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice := &Invoice{
+		Number:  number,
+		Date:    t.formatter.Now(),
+		Project: project,
+		Client:  project.Client,
+		From:    from,
+		To:      to,
+		Items:   make([]InvoiceItem, 0),
+	}
+
+	invoice.Rate, invoice.Currency, err = t.ProjectRate(projectKey)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, records := range reporter.report {
+		invoice.Records = append(invoice.Records, records...)
+	}
+
+	if len(invoice.Records) == 0 {
+		return nil, ErrNoBillableRecords
+	}
+
+	sort.Slice(invoice.Records, func(i, j int) bool {
+		return invoice.Records[i].Start.Before(invoice.Records[j].Start)
+	})
+
+	if invoice.From.IsZero() {
+		invoice.From = invoice.Records[0].Start
+	}
+
+	if invoice.To.IsZero() {
+		invoice.To = *invoice.Records[len(invoice.Records)-1].End
+	}
+
+	items := make(map[string]*InvoiceItem)
+	var descriptions []string
+
+	for _, record := range invoice.Records {
+		amount, currency, ok := reporter.amount(record)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrNoProjectRate, record.Project.Key)
+		}
+
+		if invoice.Currency != "" && currency != invoice.Currency {
+			return nil, fmt.Errorf("%w: %s and %s", ErrMixedCurrencies, invoice.Currency, currency)
+		}
+		invoice.Currency = currency
+
+		description := t.invoiceItemDescription(record, groupBy)
+
+		item, ok := items[description]
+		if !ok {
+			item = &InvoiceItem{Description: description}
+			items[description] = item
+			descriptions = append(descriptions, description)
+		}
+
+		item.Duration += record.Duration()
+		item.Amount += amount
+
+		invoice.TotalDuration += record.Duration()
+		invoice.TotalAmount += amount
+	}
+
+	// Days are added in chronological order already, tags are sorted
+	// alphabetically.
+	if groupBy == InvoiceGroupByTag {
+		sort.Strings(descriptions)
+	}
+
+	for _, description := range descriptions {
+		invoice.Items = append(invoice.Items, *items[description])
+	}
+
+	return invoice, nil
+}
+
+// MarkInvoiced marks all records of the given invoice as invoiced, so that they
+// won't be included in another invoice.
+func (t *Timetrace) MarkInvoiced(invoice *Invoice) error {
+	for _, record := range invoice.Records {
+		record.Invoice = invoice.Number
+
+		if err := t.SaveRecord(*record, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RenderInvoice renders the invoice as Markdown or HTML document, depending on
+// the format. If templateFile is empty, the default template for the format is
+// used. Otherwise, the given file is used as Go template. The template has
+// access to all fields of Invoice and the following functions:
+//
+//   - date: Formats a time as YYYY-MM-DD.
+//   - duration: Formats a duration just like the report command.
+//   - hours: Formats a duration as decimal hours, e.g. 1.50.
+//   - amount: Formats an amount and the invoice currency, e.g. 120.00 EUR.
+func (t *Timetrace) RenderInvoice(invoice *Invoice, format, templateFile string) ([]byte, error) {
+	var text string
+
+	switch format {
+	case InvoiceFormatMarkdown:
+		text = defaultMarkdownInvoiceTemplate
+	case InvoiceFormatHtml:
+		text = defaultHtmlInvoiceTemplate
+	default:
+		return nil, ErrUnknownInvoiceFormat
+	}
+
+	if templateFile != "" {
+		data, err := ioutil.ReadFile(templateFile)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	}
+
+	funcs := map[string]interface{}{
+		"date": func(date time.Time) string {
+			return date.Format(dateLayout)
+		},
+		"duration": t.Formatter().FormatDuration,
+		"hours": func(duration time.Duration) string {
+			return fmt.Sprintf("%.2f", duration.Minutes()/60)
+		},
+		"amount": func(amount float64) string {
+			return t.Formatter().FormatAmounts(map[string]float64{invoice.Currency: amount})
+		},
+	}
+
+	var buf bytes.Buffer
+
+	// HTML invoices are rendered using html/template, which escapes all
+	// values such as client details and tags properly.
+	if format == InvoiceFormatHtml {
+		tmpl, err := htmltemplate.New("invoice").Funcs(funcs).Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		if err := tmpl.Execute(&buf, invoice); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	tmpl, err := template.New("invoice").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(&buf, invoice); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// invoiceItemDescription returns the description of the line item the record
+// belongs to: Either its date or its tags.
+func (t *Timetrace) invoiceItemDescription(record *Record, groupBy string) string {
+	if groupBy == InvoiceGroupByDay {
+		return t.Formatter().PrettyDateString(record.Start)
+	}
+
+	if len(record.Tags) == 0 {
+		return untaggedItem
+	}
+
+	return t.Formatter().FormatTags(record.Tags)
+}
+
+const defaultMarkdownInvoiceTemplate = `# Invoice {{ .Number }}
+
+**Date:** {{ date .Date }}
+**Period:** {{ date .From }} – {{ date .To }}
+**Project:** {{ .Project.Key }}
+{{ with .Client }}
+## Bill to
+
+{{ .Name }}
+{{ if .Address }}{{ .Address }}
+{{ end }}{{ if .Email }}{{ .Email }}
+{{ end }}{{ end }}
+## Items
+
+| Description | Hours | Amount |
+| ----------- | ----: | -----: |
+{{ range .Items }}| {{ .Description }} | {{ hours .Duration }} | {{ amount .Amount }} |
+{{ end }}| **Total** | **{{ hours .TotalDuration }}** | **{{ amount .TotalAmount }}** |
+{{ if .Rate }}
+Hourly rate: {{ amount .Rate }}
+{{ end }}`
+
+const defaultHtmlInvoiceTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Invoice {{ .Number }}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ccc; padding: 0.4em; text-align: left; }
+td.number, th.number { text-align: right; }
+</style>
+</head>
+<body>
+<h1>Invoice {{ .Number }}</h1>
+<p>
+<strong>Date:</strong> {{ date .Date }}<br>
+<strong>Period:</strong> {{ date .From }} – {{ date .To }}<br>
+<strong>Project:</strong> {{ .Project.Key }}
+</p>
+{{ with .Client }}<h2>Bill to</h2>
+<p>
+{{ .Name }}<br>
+{{ if .Address }}{{ .Address }}<br>
+{{ end }}{{ if .Email }}{{ .Email }}
+{{ end }}</p>
+{{ end }}<h2>Items</h2>
+<table>
+<tr><th>Description</th><th class="number">Hours</th><th class="number">Amount</th></tr>
+{{ range .Items }}<tr><td>{{ .Description }}</td><td class="number">{{ hours .Duration }}</td><td class="number">{{ amount .Amount }}</td></tr>
+{{ end }}<tr><th>Total</th><th class="number">{{ hours .TotalDuration }}</th><th class="number">{{ amount .TotalAmount }}</th></tr>
+</table>
+{{ if .Rate }}<p>Hourly rate: {{ amount .Rate }}</p>
+{{ end }}</body>
+</html>
+`
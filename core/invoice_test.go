@@ -0,0 +1,115 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderInvoice(t *testing.T) {
+	date := time.Date(2021, 06, 07, 0, 00, 00, 00, time.Local)
+
+	tt := &Timetrace{
+		formatter: &Formatter{},
+	}
+
+	invoice := &Invoice{
+		Number:   "2021-001",
+		Date:     date,
+		Project:  &Project{Key: "make-coffee"},
+		Client:   &Client{Name: "Coffee & Co"},
+		From:     date,
+		To:       date,
+		Rate:     80,
+		Currency: "EUR",
+		Items: []InvoiceItem{
+			{Description: "espresso", Duration: 90 * time.Minute, Amount: 120},
+		},
+		TotalDuration: 90 * time.Minute,
+		TotalAmount:   120,
+	}
+
+	tests := map[string]struct {
+		format   string
+		expected []string
+	}{
+		"markdown": {
+			format: InvoiceFormatMarkdown,
+			expected: []string{
+				"# Invoice 2021-001",
+				"Coffee & Co",
+				"| espresso | 1.50 | 120.00 EUR |",
+				"| **Total** | **1.50** | **120.00 EUR** |",
+			},
+		},
+		"html": {
+			format: InvoiceFormatHtml,
+			expected: []string{
+				"<h1>Invoice 2021-001</h1>",
+				"Coffee &amp; Co",
+				"<td>espresso</td>",
+				"<th class=\"number\">120.00 EUR</th>",
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		data, err := tt.RenderInvoice(invoice, tc.format, "")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+
+		for _, expected := range tc.expected {
+			if !strings.Contains(string(data), expected) {
+				t.Errorf("%s: expected invoice to contain %q, have:\n%s", name, expected, string(data))
+			}
+		}
+	}
+}
+
+func TestInvoiceNumbers(t *testing.T) {
+	tt := newMemoryTimetrace(t)
+
+	if err := tt.SaveProject(Project{Key: "make-coffee", Rate: 80, Currency: "EUR"}, true); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	saveRecord := func(start time.Time) {
+		end := start.Add(time.Hour)
+		record := Record{Start: start, End: &end, Project: &Project{Key: "make-coffee"}, IsBillable: true}
+		if err := tt.SaveRecord(record, false); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	saveRecord(time.Date(2021, 06, 07, 8, 00, 00, 00, time.Local))
+
+	number, err := tt.DefaultInvoiceNumber("make-coffee")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := "make-coffee-" + tt.formatter.Now().Format("20060102")
+	if number != expected {
+		t.Errorf("expected number %s, got %s", expected, number)
+	}
+
+	invoice, err := tt.CreateInvoice(number, "make-coffee", time.Time{}, time.Time{}, InvoiceGroupByDay)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := tt.MarkInvoiced(invoice); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	saveRecord(time.Date(2021, 06, 8, 8, 00, 00, 00, time.Local))
+
+	if _, err := tt.CreateInvoice(number, "make-coffee", time.Time{}, time.Time{}, InvoiceGroupByDay); !errors.Is(err, ErrInvoiceNumberUsed) {
+		t.Errorf("expected error %v, got %v", ErrInvoiceNumberUsed, err)
+	}
+
+	if number, err := tt.DefaultInvoiceNumber("make-coffee"); err != nil || number != expected+"-2" {
+		t.Errorf("expected number %s-2, got %s and %v", expected, number, err)
+	}
+}
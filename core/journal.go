@@ -0,0 +1,473 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Kinds of resources whose changes are recorded in the journal.
+const (
+	JournalKindProject = "project"
+	JournalKindRecord  = "record"
+	JournalKindTag     = "tag"
+)
+
+// Operations of journal entries created by Undo and Redo. All other entries
+// are named after the command that made the changes.
+const (
+	journalOperationUndo = "undo"
+	journalOperationRedo = "redo"
+)
+
+var (
+	ErrNothingToUndo   = errors.New("nothing to undo")
+	ErrNothingToRedo   = errors.New("nothing to redo")
+	ErrJournalConflict = errors.New("the resource has been changed outside of timetrace, use force to overwrite it anyway")
+)
+
+// JournalEntry is an entry of the operation journal. The journal is append-only:
+// Undoing an operation doesn't remove its entry, but appends an undo entry
+// referring to it.
+type JournalEntry struct {
+	ID        int       `json:"id"`
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	// Changes contains the changes made by the operation. It is empty for
+	// undo and redo entries.
+	Changes []JournalChange `json:"changes,omitempty"`
+	// Target is the ID of the entry undone or redone by an undo or redo entry.
+	Target int `json:"target,omitempty"`
+}
+
+// JournalChange is a change of a single project, record or tag. Before and
+// After contain the stored resource before and after the change. Before is nil
+// if the resource has been created and After is nil if it has been deleted.
+type JournalChange struct {
+	Kind string `json:"kind"`
+	// Key is the project or tag key or the record key in the 24-hour format
+	// and the home time zone, e.g. 2021-05-01-15-00.
+	Key    string          `json:"key"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// HistoryEntry is a journal entry as returned by History.
+type HistoryEntry struct {
+	JournalEntry
+	// Undone is true if the operation has been undone and not been redone.
+	Undone bool
+}
+
+// Action returns a short description of the change: created, deleted or
+// modified.
+func (c *JournalChange) Action() string {
+	switch {
+	case c.Before == nil:
+		return "created"
+	case c.After == nil:
+		return "deleted"
+	default:
+		return "modified"
+	}
+}
+
+// CommitOperation appends all changes made since the last call to the journal,
+// so that they can be undone as a single operation. The operation describes the
+// changes, e.g. the command that made them. Nothing is appended if there are no
+// changes.
+func (t *Timetrace) CommitOperation(operation string) error {
+	journal, ok := t.fs.(*journalingFs)
+	if !ok || len(journal.changes) == 0 {
+		return nil
+	}
+
+	changes := journal.changes
+	journal.changes = nil
+
+	return t.appendJournalEntry(JournalEntry{
+		Operation: operation,
+		Changes:   changes,
+	})
+}
+
+// History returns all journal entries from oldest to newest.
+func (t *Timetrace) History() ([]HistoryEntry, error) {
+	entries, err := t.journalEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	undone := make(map[int]bool)
+	for _, entry := range entries {
+		switch entry.Operation {
+		case journalOperationUndo:
+			undone[entry.Target] = true
+		case journalOperationRedo:
+			undone[entry.Target] = false
+		}
+	}
+
+	history := make([]HistoryEntry, 0, len(entries))
+	for _, entry := range entries {
+		history = append(history, HistoryEntry{
+			JournalEntry: entry,
+			Undone:       undone[entry.ID],
+		})
+	}
+
+	return history, nil
+}
+
+// Undo reverts the latest n operations that haven't been undone yet, starting
+// with the latest one. Returns the undone operations, or ErrNothingToUndo if
+// there is no operation left to undo.
+//
+// If a changed resource has been modified outside of timetrace since, an error
+// wrapping ErrJournalConflict is returned unless undoing is forced.
+func (t *Timetrace) Undo(n int, force bool) ([]JournalEntry, error) {
+	return t.replayJournal(n, force, true)
+}
+
+// Redo re-applies the latest n undone operations. Operations can only be redone
+// as long as no other operation has been made after undoing them. Returns the
+// redone operations, or ErrNothingToRedo if there is no operation to redo.
+func (t *Timetrace) Redo(n int, force bool) ([]JournalEntry, error) {
+	return t.replayJournal(n, force, false)
+}
+
+// replayJournal undoes or redoes the latest n operations.
+func (t *Timetrace) replayJournal(n int, force, undo bool) ([]JournalEntry, error) {
+	entries, err := t.journalEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	done, undone := journalStacks(entries)
+
+	stack, operation, errEmpty := done, journalOperationUndo, ErrNothingToUndo
+	if !undo {
+		stack, operation, errEmpty = undone, journalOperationRedo, ErrNothingToRedo
+	}
+
+	if len(stack) == 0 {
+		return nil, errEmpty
+	}
+
+	var replayed []JournalEntry
+
+	for i := 0; i < n && len(stack) > 0; i++ {
+		entry := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if err := t.applyJournalChanges(entry.Changes, force, undo); err != nil {
+			return replayed, fmt.Errorf("%s %s: %w", operation, entry.Operation, err)
+		}
+
+		if err := t.appendJournalEntry(JournalEntry{Operation: operation, Target: entry.ID}); err != nil {
+			return replayed, err
+		}
+
+		replayed = append(replayed, entry)
+	}
+
+	return replayed, nil
+}
+
+// applyJournalChanges restores the state before the changes if undo is set, or
+// the state after the changes otherwise. The changes bypass the journal, since
+// they are recorded as undo or redo entry instead.
+func (t *Timetrace) applyJournalChanges(changes []JournalChange, force, undo bool) error {
+	fs := t.fs
+	if journal, ok := fs.(*journalingFs); ok {
+		fs = journal.Filesystem
+	}
+
+	// Check all resources first, so that either all or no changes are applied.
+	if !force {
+		for _, change := range changes {
+			expected := change.After
+			if !undo {
+				expected = change.Before
+			}
+
+			current, err := loadJournalResource(fs, t.formatter.Location(), change.Kind, change.Key)
+			if err != nil {
+				return err
+			}
+
+			if !sameResource(current, expected) {
+				return fmt.Errorf("%s %s: %w", change.Kind, change.Key, ErrJournalConflict)
+			}
+		}
+	}
+
+	for i := range changes {
+		change := changes[i]
+		target := change.After
+
+		// Changes are undone in reverse order.
+		if undo {
+			change = changes[len(changes)-1-i]
+			target = change.Before
+		}
+
+		// The journal stores resources in compact form, but resources
+		// are stored indented like everywhere else.
+		if target != nil {
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, target, "", "\t"); err != nil {
+				return err
+			}
+			target = indented.Bytes()
+		}
+
+		if err := storeJournalResource(fs, t.formatter.Location(), change.Kind, change.Key, target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *Timetrace) journalEntries() ([]JournalEntry, error) {
+	data, err := t.fs.JournalEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]JournalEntry, 0, len(data))
+
+	for _, entryData := range data {
+		var entry JournalEntry
+		// An entry may have been written partially if timetrace has been
+		// interrupted. Such an entry is skipped, just as if the operation
+		// hadn't been recorded at all.
+		if err := json.Unmarshal(entryData, &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func (t *Timetrace) appendJournalEntry(entry JournalEntry) error {
+	latestID, err := t.latestJournalID()
+	if err != nil {
+		return err
+	}
+
+	entry.ID = latestID + 1
+	entry.Time = t.formatter.Now()
+
+	data, err := json.Marshal(&entry)
+	if err != nil {
+		return err
+	}
+
+	return t.fs.AppendJournalEntry(data)
+}
+
+// latestJournalID returns the ID of the latest journal entry, or 0 if the
+// journal is empty. Only the latest entry is read, unless it has been written
+// partially. In that case, the ID is taken from the latest complete entry.
+func (t *Timetrace) latestJournalID() (int, error) {
+	data, err := t.fs.LatestJournalEntry()
+	if err != nil || data == nil {
+		return 0, err
+	}
+
+	var latest JournalEntry
+	if err := json.Unmarshal(data, &latest); err == nil {
+		return latest.ID, nil
+	}
+
+	entries, err := t.journalEntries()
+	if err != nil || len(entries) == 0 {
+		return 0, err
+	}
+
+	return entries[len(entries)-1].ID, nil
+}
+
+// journalStacks returns the operations that can be undone and the operations
+// that can be redone, each ordered from oldest to newest. Making a new
+// operation clears the operations that can be redone.
+func journalStacks(entries []JournalEntry) ([]JournalEntry, []JournalEntry) {
+	byID := make(map[int]JournalEntry)
+	var done, undone []JournalEntry
+
+	for _, entry := range entries {
+		switch entry.Operation {
+		case journalOperationUndo:
+			if len(done) > 0 && done[len(done)-1].ID == entry.Target {
+				done = done[:len(done)-1]
+				undone = append(undone, byID[entry.Target])
+			}
+		case journalOperationRedo:
+			if len(undone) > 0 && undone[len(undone)-1].ID == entry.Target {
+				undone = undone[:len(undone)-1]
+				done = append(done, byID[entry.Target])
+			}
+		default:
+			byID[entry.ID] = entry
+			done = append(done, entry)
+			undone = nil
+		}
+	}
+
+	return done, undone
+}
+
+func loadJournalResource(fs Filesystem, location *time.Location, kind, key string) ([]byte, error) {
+	var data []byte
+	var err error
+
+	switch kind {
+	case JournalKindProject:
+		data, err = fs.LoadProject(key)
+	case JournalKindTag:
+		data, err = fs.LoadTag(key)
+	case JournalKindRecord:
+		start, parseErr := time.ParseInLocation(defaultRecordKeyLayout, key, location)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		data, err = fs.LoadRecord(start)
+	default:
+		return nil, fmt.Errorf("unknown journal kind %s", kind)
+	}
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	return data, err
+}
+
+// storeJournalResource saves the given data as resource, or deletes the
+// resource if data is nil. Record keys are parsed in the given location.
+func storeJournalResource(fs Filesystem, location *time.Location, kind, key string, data []byte) error {
+	var err error
+
+	switch kind {
+	case JournalKindProject:
+		if data == nil {
+			err = fs.DeleteProject(key)
+		} else {
+			err = fs.SaveProject(key, data)
+		}
+	case JournalKindTag:
+		if data == nil {
+			err = fs.DeleteTag(key)
+		} else {
+			err = fs.SaveTag(key, data)
+		}
+	case JournalKindRecord:
+		start, parseErr := time.ParseInLocation(defaultRecordKeyLayout, key, location)
+		if parseErr != nil {
+			return parseErr
+		}
+		if data == nil {
+			err = fs.DeleteRecord(start)
+		} else {
+			err = fs.SaveRecord(start, data)
+		}
+	default:
+		return fmt.Errorf("unknown journal kind %s", kind)
+	}
+
+	// Deleting a resource that doesn't exist is fine when forced.
+	if data == nil && errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+// sameResource checks if two stored resources are equal, ignoring whitespace.
+func sameResource(a, b []byte) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	var compactA, compactB bytes.Buffer
+
+	if json.Compact(&compactA, a) != nil || json.Compact(&compactB, b) != nil {
+		return bytes.Equal(a, b)
+	}
+
+	return bytes.Equal(compactA.Bytes(), compactB.Bytes())
+}
+
+// journalingFs wraps a storage backend and records all changes of projects,
+// records and tags until they're committed using CommitOperation. Changes of
+// backups aren't recorded.
+type journalingFs struct {
+	Filesystem
+	// location is the home time zone record keys are formatted in.
+	location *time.Location
+	changes  []JournalChange
+}
+
+func (j *journalingFs) SaveProject(key string, data []byte) error {
+	return j.record(JournalKindProject, key, data)
+}
+
+func (j *journalingFs) DeleteProject(key string) error {
+	return j.record(JournalKindProject, key, nil)
+}
+
+func (j *journalingFs) SaveRecord(start time.Time, data []byte) error {
+	return j.record(JournalKindRecord, start.In(j.location).Format(defaultRecordKeyLayout), data)
+}
+
+func (j *journalingFs) DeleteRecord(start time.Time) error {
+	return j.record(JournalKindRecord, start.In(j.location).Format(defaultRecordKeyLayout), nil)
+}
+
+func (j *journalingFs) SaveTag(key string, data []byte) error {
+	return j.record(JournalKindTag, key, data)
+}
+
+func (j *journalingFs) DeleteTag(key string) error {
+	return j.record(JournalKindTag, key, nil)
+}
+
+// record stores or deletes the resource and records the change. If the resource
+// has been changed before within the same operation, the changes are merged.
+func (j *journalingFs) record(kind, key string, data []byte) error {
+	before, err := loadJournalResource(j.Filesystem, j.location, kind, key)
+	if err != nil {
+		return err
+	}
+
+	if err := storeJournalResource(j.Filesystem, j.location, kind, key, data); err != nil {
+		return err
+	}
+
+	// storeJournalResource ignores deleting a resource that doesn't exist.
+	if data == nil && before == nil {
+		return fmt.Errorf("%s %s: %w", kind, key, os.ErrNotExist)
+	}
+
+	for i := range j.changes {
+		if j.changes[i].Kind == kind && j.changes[i].Key == key {
+			j.changes[i].After = data
+			return nil
+		}
+	}
+
+	j.changes = append(j.changes, JournalChange{
+		Kind:   kind,
+		Key:    key,
+		Before: before,
+		After:  data,
+	})
+
+	return nil
+}
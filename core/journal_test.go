@@ -0,0 +1,222 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"testing"
+	"time"
+)
+
+// memoryFs is a storage backend that only stores projects, records, record
+// backups and the journal in memory. Calling any other method panics.
+type memoryFs struct {
+	Filesystem
+	projects map[string][]byte
+	records  map[int64][]byte
+	backups  map[int64][]byte
+	journal  [][]byte
+}
+
+func (m *memoryFs) RecordKeys(from, to time.Time) ([]time.Time, error) {
+	keys := make([]time.Time, 0)
+	for key := range m.records {
+		start := time.Unix(key, 0)
+		if (from.IsZero() || !start.Before(from)) && (to.IsZero() || start.Before(to)) {
+			keys = append(keys, start)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Before(keys[j])
+	})
+	return keys, nil
+}
+
+func (m *memoryFs) LatestRecordKey() (time.Time, error) {
+	keys, _ := m.RecordKeys(time.Time{}, time.Time{})
+	if len(keys) == 0 {
+		return time.Time{}, nil
+	}
+	return keys[len(keys)-1], nil
+}
+
+func (m *memoryFs) LoadRecord(start time.Time) ([]byte, error) {
+	data, ok := m.records[start.Truncate(time.Minute).Unix()]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return data, nil
+}
+
+func (m *memoryFs) SaveRecord(start time.Time, data []byte) error {
+	m.records[start.Truncate(time.Minute).Unix()] = data
+	return nil
+}
+
+func (m *memoryFs) LoadRecordBackup(start time.Time) ([]byte, error) {
+	data, ok := m.backups[start.Truncate(time.Minute).Unix()]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return data, nil
+}
+
+func (m *memoryFs) SaveRecordBackup(start time.Time, data []byte) error {
+	m.backups[start.Truncate(time.Minute).Unix()] = data
+	return nil
+}
+
+func (m *memoryFs) DeleteRecord(start time.Time) error {
+	if _, ok := m.records[start.Truncate(time.Minute).Unix()]; !ok {
+		return os.ErrNotExist
+	}
+	delete(m.records, start.Truncate(time.Minute).Unix())
+	return nil
+}
+
+func (m *memoryFs) ProjectKeys() ([]string, error) {
+	keys := make([]string, 0, len(m.projects))
+	for key := range m.projects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (m *memoryFs) LoadProject(key string) ([]byte, error) {
+	data, ok := m.projects[key]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return data, nil
+}
+
+func (m *memoryFs) SaveProject(key string, data []byte) error {
+	m.projects[key] = data
+	return nil
+}
+
+func (m *memoryFs) DeleteProject(key string) error {
+	if _, ok := m.projects[key]; !ok {
+		return os.ErrNotExist
+	}
+	delete(m.projects, key)
+	return nil
+}
+
+func (m *memoryFs) JournalEntries() ([][]byte, error) {
+	return m.journal, nil
+}
+
+func (m *memoryFs) LatestJournalEntry() ([]byte, error) {
+	if len(m.journal) == 0 {
+		return nil, nil
+	}
+	return m.journal[len(m.journal)-1], nil
+}
+
+func (m *memoryFs) AppendJournalEntry(data []byte) error {
+	m.journal = append(m.journal, data)
+	return nil
+}
+
+func TestUndoRedo(t *testing.T) {
+	store := &memoryFs{projects: make(map[string][]byte)}
+	tt := &Timetrace{
+		fs:        &journalingFs{Filesystem: store, location: time.Local},
+		formatter: &Formatter{},
+	}
+
+	mustCommit := func(operation string, change func() error) {
+		if err := change(); err != nil {
+			t.Fatalf("%s: unexpected error: %s", operation, err)
+		}
+		if err := tt.CommitOperation(operation); err != nil {
+			t.Fatalf("%s: unexpected error: %s", operation, err)
+		}
+	}
+
+	mustCommit("create", func() error {
+		return tt.fs.SaveProject("make-coffee", []byte(`{"key": "make-coffee"}`))
+	})
+	mustCommit("edit", func() error {
+		return tt.fs.SaveProject("make-coffee", []byte(`{"key": "make-coffee", "rate": 50}`))
+	})
+	mustCommit("delete", func() error {
+		return tt.fs.DeleteProject("make-coffee")
+	})
+
+	expectProject := func(step, expected string) {
+		if data := string(store.projects["make-coffee"]); data != expected {
+			t.Errorf("%s: expected project %q, got %q", step, expected, data)
+		}
+	}
+
+	if _, err := tt.Undo(2, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expectProject("undo delete and edit", "{\n\t\"key\": \"make-coffee\"\n}")
+
+	if _, err := tt.Redo(1, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expectProject("redo edit", "{\n\t\"key\": \"make-coffee\",\n\t\"rate\": 50\n}")
+
+	// Changes made outside of the journal conflict with undoing.
+	store.projects["make-coffee"] = []byte(`{"key": "make-coffee", "rate": 60}`)
+
+	if _, err := tt.Undo(1, false); !errors.Is(err, ErrJournalConflict) {
+		t.Fatalf("expected error %v, got %v", ErrJournalConflict, err)
+	}
+
+	if _, err := tt.Undo(1, true); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expectProject("forced undo edit", "{\n\t\"key\": \"make-coffee\"\n}")
+
+	// A new operation clears the operations that can be redone.
+	mustCommit("create", func() error {
+		return tt.fs.SaveProject("grind-beans", []byte(`{"key": "grind-beans"}`))
+	})
+
+	if _, err := tt.Redo(1, false); !errors.Is(err, ErrNothingToRedo) {
+		t.Errorf("expected error %v, got %v", ErrNothingToRedo, err)
+	}
+
+	history, err := tt.History()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	undone := 0
+	for _, entry := range history {
+		if entry.Undone {
+			undone++
+		}
+	}
+
+	if len(history) != 8 || undone != 2 {
+		t.Errorf("expected 8 history entries with 2 undone operations, got %d with %d", len(history), undone)
+	}
+}
+
+func TestAppendJournalEntryAfterPartialEntry(t *testing.T) {
+	store := &memoryFs{journal: [][]byte{[]byte(`{"id": 3, "operation": "edit"}`), []byte(`{"id": 4, "oper`)}}
+	tt := &Timetrace{
+		fs:        &journalingFs{Filesystem: store, location: time.Local},
+		formatter: &Formatter{},
+	}
+
+	if err := tt.appendJournalEntry(JournalEntry{Operation: "delete"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	entries, err := tt.journalEntries()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if latest := entries[len(entries)-1]; latest.ID != 4 || latest.Operation != "delete" {
+		t.Errorf("expected entry 4 to follow the latest complete entry, got %+v", latest)
+	}
+}
@@ -1,13 +1,13 @@
 package core
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 )
 
@@ -20,10 +20,17 @@ var (
 	ErrBackupProjectNotFound = errors.New("backup project not found")
 	ErrProjectAlreadyExists  = errors.New("project already exists")
 	ErrParentlessModule      = errors.New("no parent project for module exists, please create parent first")
+	ErrEditAborted           = errors.New("edit aborted, the file has been cleared")
+	ErrEditConflict          = errors.New("the file has been changed by another timetrace process while editing, please edit it again")
 )
 
 type Project struct {
 	Key string `json:"key"`
+	// Rate is the hourly rate billed for the project in the given Currency.
+	Rate     float64 `json:"rate,omitempty"`
+	Currency string  `json:"currency,omitempty"`
+	// Client contains the details printed on invoices for the project.
+	Client *Client `json:"client,omitempty"`
 }
 
 // Parent returns the parent project of the current project or an empty string
@@ -42,16 +49,55 @@ func (p *Project) IsModule() bool {
 	return p.Parent() != ""
 }
 
+// ProjectRate returns the hourly rate and currency of the project with the
+// given key. A rate stored in the project itself takes precedence over a rate
+// configured in the config file. If the project is a module without a rate,
+// the rate of its parent project is returned. A rate of 0 means that there is
+// no rate for the project.
+func (t *Timetrace) ProjectRate(key string) (float64, string, error) {
+	project, err := t.LoadProject(key)
+	if err != nil && !errors.Is(err, ErrProjectNotFound) {
+		return 0, "", err
+	}
+
+	if project != nil && project.Rate != 0 {
+		return project.Rate, project.Currency, nil
+	}
+
+	if projectConfig, ok := t.config.Projects[key]; ok && projectConfig.Rate != 0 {
+		return projectConfig.Rate, projectConfig.Currency, nil
+	}
+
+	if parent := (&Project{Key: key}).Parent(); parent != "" {
+		return t.ProjectRate(parent)
+	}
+
+	return 0, "", nil
+}
+
+// projectBillable returns the billable default configured for the project.
+// Just like for the start command, only the configuration of the project key
+// itself is used.
+func (t *Timetrace) projectBillable(key string) bool {
+	return t.config.Projects[key].Billable
+}
+
 // LoadProject loads the project with the given key. Returns ErrProjectNotFound
 // if the project cannot be found.
 func (t *Timetrace) LoadProject(key string) (*Project, error) {
-	path := t.fs.ProjectFilepath(key)
-	return t.loadProject(path)
+	data, err := t.fs.LoadProject(key)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrProjectNotFound
+	}
+	return t.loadProject(data, err)
 }
 
 func (t *Timetrace) LoadBackupProject(key string) (*Project, error) {
-	path := t.fs.ProjectBackupFilepath(key)
-	return t.loadProject(path)
+	data, err := t.fs.LoadProjectBackup(key)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBackupProjectNotFound
+	}
+	return t.loadProject(data, err)
 }
 
 // ListProjectModules loads all modules for a project and returns their keys as a concatenated string
@@ -81,15 +127,15 @@ func (t *Timetrace) ListProjectModules(project *Project) (string, error) {
 // ListProjects loads and returns all stored projects sorted by their filenames.
 // If no projects are found, an empty slice and no error will be returned.
 func (t *Timetrace) ListProjects() ([]*Project, error) {
-	paths, err := t.fs.ProjectFilepaths()
+	keys, err := t.fs.ProjectKeys()
 	if err != nil {
 		return nil, err
 	}
 
 	projects := make([]*Project, 0)
 
-	for _, path := range paths {
-		project, err := t.loadProject(path)
+	for _, key := range keys {
+		project, err := t.LoadProject(key)
 		if err != nil {
 			return nil, err
 		}
@@ -109,24 +155,16 @@ func (t *Timetrace) SaveProject(project Project, force bool) error {
 		}
 	}
 
-	path := t.fs.ProjectFilepath(project.Key)
-	if _, err := os.Stat(path); err == nil && !force {
+	if _, err := t.fs.LoadProject(project.Key); err == nil && !force {
 		return ErrProjectAlreadyExists
 	}
 
-	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		return err
-	}
-
 	bytes, err := json.MarshalIndent(&project, "", "\t")
 	if err != nil {
 		return err
 	}
 
-	_, err = file.Write(bytes)
-
-	return err
+	return t.fs.SaveProject(project.Key, bytes)
 }
 
 // BackupProject creates a backup of the given project file.
@@ -136,27 +174,18 @@ func (t *Timetrace) BackupProject(projectKey string) error {
 		return err
 	}
 
-	path := t.fs.ProjectBackupFilepath(projectKey)
-
-	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		return err
-	}
-
 	bytes, err := json.MarshalIndent(&project, "", "\t")
 	if err != nil {
 		return err
 	}
 
-	_, err = file.Write(bytes)
-
-	return err
+	return t.fs.SaveProjectBackup(projectKey, bytes)
 }
 
 // RevertProject reverts the given project to its latest backup
 func (t *Timetrace) RevertProject(projectKey string) error {
-	// get all backup filepaths
-	backups, err := t.fs.ProjectBackupFilepaths()
+	// get all backup keys
+	backups, err := t.fs.ProjectBackupKeys()
 	if err != nil {
 		return err
 	}
@@ -164,9 +193,8 @@ func (t *Timetrace) RevertProject(projectKey string) error {
 	// get submodules associated with projectKey
 	var submodules []string
 	for _, backup := range backups {
-		if strings.HasSuffix(backup, fmt.Sprintf("@%s.json.bak", projectKey)) {
-			submoduleKey := strings.TrimSuffix(filepath.Base(backup), ".json.bak")
-			submodules = append(submodules, submoduleKey)
+		if strings.HasSuffix(backup, fmt.Sprintf("@%s", projectKey)) {
+			submodules = append(submodules, backup)
 		}
 	}
 
@@ -182,21 +210,57 @@ func (t *Timetrace) RevertProject(projectKey string) error {
 	return err
 }
 
-// EditProject opens the project file in the preferred or default editor .
+// EditProject opens the project in the preferred or default editor. The stored
+// project is only replaced once the edited project is valid, see editInEditor.
+// If the project has been changed by another process in the meantime,
+// ErrEditConflict is returned.
 func (t *Timetrace) EditProject(projectKey string) error {
-	if _, err := t.LoadProject(projectKey); err != nil {
+	data, err := t.fs.LoadProject(projectKey)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrProjectNotFound
+	} else if err != nil {
 		return err
 	}
 
-	editor := t.editorFromEnvironment()
-	path := t.fs.ProjectFilepath(projectKey)
+	var project *Project
 
-	cmd := exec.Command(editor, path)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	_, err = t.editInEditor(data, func(data []byte) (err error) {
+		project, err = t.validateEditedProject(projectKey, data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
 
-	return cmd.Run()
+	// The store isn't locked while the editor is open, see runEditor.
+	if current, err := t.fs.LoadProject(projectKey); err != nil || !bytes.Equal(current, data) {
+		return ErrEditConflict
+	}
+
+	return t.SaveProject(*project, true)
+}
+
+// validateEditedProject checks if the data edited by the user is a valid
+// project that can be stored under the given key and returns the project.
+// Modules need an existing parent project.
+func (t *Timetrace) validateEditedProject(projectKey string, data []byte) (*Project, error) {
+	var project Project
+
+	if err := decodeStrict(data, &project); err != nil {
+		return nil, fmt.Errorf("invalid project: %s", err)
+	}
+
+	if project.Key != projectKey {
+		return nil, fmt.Errorf("project key %s doesn't match %s, the key can't be changed", project.Key, projectKey)
+	}
+
+	if project.IsModule() {
+		if err := t.assertParent(project); err != nil {
+			return nil, err
+		}
+	}
+
+	return &project, nil
 }
 
 // DeleteProject removes the given project and any associated submodules. Returns ErrProjectNotFound if the
@@ -219,21 +283,16 @@ func (t *Timetrace) DeleteProject(project Project) error {
 	return t.delete(project.Key)
 }
 
-func (t *Timetrace) loadProject(path string) (*Project, error) {
-	file, err := ioutil.ReadFile(path)
+// loadProject decodes the project data returned by the storage backend. The
+// error returned along with the data is passed through.
+func (t *Timetrace) loadProject(data []byte, err error) (*Project, error) {
 	if err != nil {
-		if os.IsNotExist(err) {
-			if strings.HasSuffix(path, ".bak") {
-				return nil, ErrBackupProjectNotFound
-			}
-			return nil, ErrProjectNotFound
-		}
 		return nil, err
 	}
 
 	var project Project
 
-	if err := json.Unmarshal(file, &project); err != nil {
+	if err := json.Unmarshal(data, &project); err != nil {
 		return nil, err
 	}
 
@@ -273,6 +332,102 @@ func (t *Timetrace) editorFromEnvironment() string {
 	return defaultEditor
 }
 
+// editorCommentPrefix marks the lines added to the top of the edited file to
+// explain why the previous edit has been rejected. These lines are removed
+// before the data is validated.
+const editorCommentPrefix = "//"
+
+// editInEditor writes the given data to a temporary file, opens that file in
+// the preferred or default editor and returns the file contents once the editor
+// has been closed.
+//
+// The contents are passed to validate. If they are invalid, the file is opened
+// again with the validation error on top of it until the contents are valid or
+// the user clears the file, in which case ErrEditAborted is returned.
+func (t *Timetrace) editInEditor(data []byte, validate func([]byte) error) ([]byte, error) {
+	file, err := ioutil.TempFile("", "timetrace-*.json")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	for {
+		if err := ioutil.WriteFile(file.Name(), data, 0600); err != nil {
+			return nil, err
+		}
+
+		if err := t.runEditor(file.Name()); err != nil {
+			return nil, err
+		}
+
+		edited, err := ioutil.ReadFile(file.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		edited = stripEditorComments(edited)
+
+		if len(bytes.TrimSpace(edited)) == 0 {
+			return nil, ErrEditAborted
+		}
+
+		err = validate(edited)
+		if err == nil {
+			return edited, nil
+		}
+
+		data = append([]byte(fmt.Sprintf("%s Error: %s\n%s Fix the error and save the file or clear it to abort.\n",
+			editorCommentPrefix, err, editorCommentPrefix)), edited...)
+	}
+}
+
+// runEditor opens the file in the preferred or default editor and waits until
+// the editor has been closed. If the store is locked, the lock is released while
+// the editor is open, so that an edit session doesn't block other timetrace
+// processes, and acquired again afterwards.
+func (t *Timetrace) runEditor(filename string) error {
+	locked := t.locked
+	if locked {
+		if err := t.Unlock(); err != nil {
+			return err
+		}
+	}
+
+	cmd := exec.Command(t.editorFromEnvironment(), filename)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err := cmd.Run()
+
+	if locked {
+		if lockErr := t.Lock(); lockErr != nil {
+			return lockErr
+		}
+	}
+
+	return err
+}
+
+// stripEditorComments removes the comment lines added by editInEditor from the
+// top of the data.
+func stripEditorComments(data []byte) []byte {
+	for bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte(editorCommentPrefix)) {
+		data = bytes.TrimLeft(data, " \t\r\n")
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			return nil
+		}
+		data = data[end+1:]
+	}
+
+	return data
+}
+
 func (t *Timetrace) assertParent(project Project) error {
 	allP, err := t.ListProjects()
 	if err != nil {
@@ -294,30 +449,19 @@ func (t *Timetrace) revert(key string) error {
 		return err
 	}
 
-	// get filepath of reverted file
-	path := t.fs.ProjectFilepath(key)
-
-	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		return err
-	}
-
 	bytes, err := json.MarshalIndent(&project, "", "\t")
 	if err != nil {
 		return err
 	}
 
-	_, err = file.Write(bytes)
-
-	return err
+	return t.fs.SaveProject(key, bytes)
 }
 
 func (t *Timetrace) delete(key string) error {
-	path := t.fs.ProjectFilepath(key)
-
-	if _, err := os.Stat(path); os.IsNotExist(err) {
+	err := t.fs.DeleteProject(key)
+	if errors.Is(err, os.ErrNotExist) {
 		return ErrProjectNotFound
 	}
 
-	return os.Remove(path)
+	return err
 }
@@ -0,0 +1,195 @@
+package core
+
+import (
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dominikbraun/timetrace/config"
+)
+
+func TestStripEditorComments(t *testing.T) {
+	tests := map[string]struct {
+		data     string
+		expected string
+	}{
+		"no comments": {
+			data:     "{\n\t\"key\": \"make-coffee\"\n}",
+			expected: "{\n\t\"key\": \"make-coffee\"\n}",
+		},
+		"error comments": {
+			data:     "// Error: invalid project\n// Fix the error.\n{\n\t\"key\": \"make-coffee\"\n}",
+			expected: "{\n\t\"key\": \"make-coffee\"\n}",
+		},
+		"only comments": {
+			data:     "// Error: invalid project",
+			expected: "",
+		},
+	}
+
+	for name, tc := range tests {
+		if stripped := string(stripEditorComments([]byte(tc.data))); stripped != tc.expected {
+			t.Errorf("%s: expected %q, got %q", name, tc.expected, stripped)
+		}
+	}
+}
+
+func TestEditInEditor(t *testing.T) {
+	// true leaves the file untouched, just like a user saving it unchanged.
+	editor, err := exec.LookPath("true")
+	if err != nil {
+		t.Skip("true command is not available")
+	}
+
+	tt := &Timetrace{config: &config.Config{Editor: editor}}
+
+	var validated []string
+
+	edited, err := tt.editInEditor([]byte(`{"key": "make-coffee"}`), func(data []byte) error {
+		validated = append(validated, string(data))
+		if len(validated) == 1 {
+			return errors.New("invalid project")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(validated) != 2 {
+		t.Fatalf("expected the file to be opened twice, got %d times", len(validated))
+	}
+
+	for _, data := range validated {
+		if strings.Contains(data, editorCommentPrefix) {
+			t.Errorf("expected error comments to be stripped, got %q", data)
+		}
+	}
+
+	if string(edited) != `{"key": "make-coffee"}` {
+		t.Errorf("unexpected edited data %q", edited)
+	}
+
+	_, err = tt.editInEditor([]byte(""), func(data []byte) error {
+		return nil
+	})
+	if !errors.Is(err, ErrEditAborted) {
+		t.Errorf("expected error %v, got %v", ErrEditAborted, err)
+	}
+}
+
+func TestValidateEditedProject(t *testing.T) {
+	tests := map[string]struct {
+		key         string
+		data        string
+		expectedErr bool
+	}{
+		"valid project": {
+			key:  "make-coffee",
+			data: `{"key": "make-coffee"}`,
+		},
+		"trailing data": {
+			key:         "make-coffee",
+			data:        `{"key": "make-coffee"} }garbage`,
+			expectedErr: true,
+		},
+		"second value": {
+			key:         "make-coffee",
+			data:        `{"key": "make-coffee"} {"key": "make-coffee"}`,
+			expectedErr: true,
+		},
+		"changed key": {
+			key:         "make-coffee",
+			data:        `{"key": "make-tea"}`,
+			expectedErr: true,
+		},
+		"module": {
+			key:  "grind-beans@make-coffee",
+			data: `{"key": "grind-beans@make-coffee"}`,
+		},
+		"module without parent": {
+			key:         "boil-water@make-tea",
+			data:        `{"key": "boil-water@make-tea"}`,
+			expectedErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		tt := newMemoryTimetrace(t)
+
+		project, err := tt.validateEditedProject(tc.key, []byte(tc.data))
+		if tc.expectedErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+
+		if project.Key != tc.key {
+			t.Errorf("%s: expected project %s, got %s", name, tc.key, project.Key)
+		}
+	}
+}
+
+// lockingFs is a memoryFs recording how the store lock is acquired and
+// released. onLock is called whenever the lock is acquired.
+type lockingFs struct {
+	*memoryFs
+	calls  []string
+	onLock func()
+}
+
+func (l *lockingFs) Lock(timeout time.Duration) error {
+	l.calls = append(l.calls, "lock")
+	if l.onLock != nil {
+		l.onLock()
+	}
+	return nil
+}
+
+func (l *lockingFs) Unlock() error {
+	l.calls = append(l.calls, "unlock")
+	return nil
+}
+
+func TestEditProjectReleasesLock(t *testing.T) {
+	// true leaves the file untouched, just like a user saving it unchanged.
+	editor, err := exec.LookPath("true")
+	if err != nil {
+		t.Skip("true command is not available")
+	}
+
+	tt := newMemoryTimetrace(t)
+	tt.config.Editor = editor
+
+	journal := tt.fs.(*journalingFs)
+	store := &lockingFs{memoryFs: journal.Filesystem.(*memoryFs)}
+	journal.Filesystem = store
+
+	if err := tt.Lock(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := tt.EditProject("make-coffee"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if expected := []string{"lock", "unlock", "lock"}; !reflect.DeepEqual(store.calls, expected) {
+		t.Errorf("expected lock calls %v, got %v", expected, store.calls)
+	}
+
+	// Another process changes the project while the editor is open.
+	store.onLock = func() {
+		store.projects["make-coffee"] = []byte(`{"key": "make-coffee", "rate": 50}`)
+	}
+
+	if err := tt.EditProject("make-coffee"); !errors.Is(err, ErrEditConflict) {
+		t.Errorf("expected error %v, got %v", ErrEditConflict, err)
+	}
+}
@@ -1,77 +1,314 @@
 package core
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
-	"io/ioutil"
+	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
 
-const (
-	recordLayout = "15-04"
-)
-
 var (
 	ErrRecordNotFound       = errors.New("record not found")
 	ErrBackupRecordNotFound = errors.New("backup record not found")
 	ErrRecordAlreadyExists  = errors.New("record already exists")
+	ErrRecordLocked         = errors.New("record is locked or has been invoiced, use force to modify it anyway")
+	ErrRecordCollides       = errors.New("record collides with other records")
+	ErrPauseOutsideRecord   = errors.New("record has pauses outside of its start and end time")
+	ErrInvalidRecordID      = errors.New("invalid record ID, use @<N> for today's records or <DATE>@<N>")
 )
 
+// recordIDSeparator separates the date and the number of a record ID.
+const recordIDSeparator = "@"
+
 type Record struct {
 	Start      time.Time  `json:"start"`
 	End        *time.Time `json:"end"`
 	Project    *Project   `json:"project"`
 	IsBillable bool       `json:"is_billable"`
 	Tags       []string   `json:"tags"`
+	Pauses     []Pause    `json:"pauses,omitempty"`
+	IsParallel bool       `json:"is_parallel,omitempty"`
+	Note       string     `json:"note,omitempty"`
+	// Invoice is the number of the invoice the record has been billed with.
+	Invoice string `json:"invoice,omitempty"`
+	// Locked records can't be edited, reverted or deleted without force.
+	Locked bool `json:"locked,omitempty"`
+}
+
+// Pause represents an interruption of a record. No time is tracked for a record
+// while it is paused. If the pause doesn't have an end time, it is still active.
+type Pause struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end"`
+}
+
+// IsLocked checks if the record has been locked explicitly or has been
+// included in an invoice. Locked records must only be modified when forced.
+func (r *Record) IsLocked() bool {
+	return r.Locked || r.Invoice != ""
 }
 
 // Duration calculates time duration for a specific record. If the record doesn't
 // have an end time, then it is expected that time is still being tracked, and
-// duration will be counted to a current time since start.
+// duration will be counted to a current time since start. The time the record
+// has been paused is not included.
 func (r *Record) Duration() time.Duration {
+	var duration time.Duration
+
+	if r.End != nil {
+		duration = r.End.Sub(r.Start)
+	} else {
+		duration = time.Since(r.Start)
+	}
+
+	return duration - r.PauseDuration()
+}
+
+// DurationOn calculates the time the record has been active on the day of the
+// given date. For records spanning midnight, only the part within that day is
+// counted. The time the record has been paused is not included.
+func (r *Record) DurationOn(date time.Time) time.Duration {
+	from, to := dayRange(date)
+	return r.durationWithin(from, to)
+}
+
+// durationWithin calculates the time the record has been active within the
+// given time range.
+func (r *Record) durationWithin(from, to time.Time) time.Duration {
+	var duration time.Duration
+
+	for _, interval := range r.activeIntervals() {
+		start, end := interval[0], interval[1]
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			duration += end.Sub(start)
+		}
+	}
+
+	return duration
+}
+
+// splitAtMidnight splits the record into one part per day it spans. The first
+// part starts at the start of the record, all other parts start at midnight.
+// Each part contains the pauses within its day. A record that doesn't span
+// midnight is returned as single part.
+func (r *Record) splitAtMidnight() []*Record {
+	var parts []*Record
+
+	start := r.Start
+	end := r.endOrNow()
+
+	for {
+		_, midnight := dayRange(start)
+
+		part := *r
+		part.Start = start
+		part.Pauses = nil
+
+		last := !midnight.Before(end)
+		if !last {
+			partEnd := midnight
+			part.End = &partEnd
+		}
+
+		for _, pause := range r.Pauses {
+			if pause.End != nil && !pause.End.After(start) {
+				continue
+			}
+			if !last && !pause.Start.Before(midnight) {
+				continue
+			}
+
+			clipped := pause
+			if clipped.Start.Before(start) {
+				clipped.Start = start
+			}
+			if !last && (clipped.End == nil || clipped.End.After(midnight)) {
+				pauseEnd := midnight
+				clipped.End = &pauseEnd
+			}
+			part.Pauses = append(part.Pauses, clipped)
+		}
+
+		parts = append(parts, &part)
+
+		if last {
+			return parts
+		}
+
+		start = midnight
+	}
+}
+
+// PauseDuration calculates the total time the record has been paused. An active
+// pause is counted up to the end time of the record, or up to the current time
+// if the record doesn't have an end time.
+func (r *Record) PauseDuration() time.Duration {
+	var duration time.Duration
+
+	for _, pause := range r.Pauses {
+		switch {
+		case pause.End != nil:
+			duration += pause.End.Sub(pause.Start)
+		case r.End != nil:
+			duration += r.End.Sub(pause.Start)
+		default:
+			duration += time.Since(pause.Start)
+		}
+	}
+
+	return duration
+}
+
+// IsPaused checks if the record has an active pause.
+func (r *Record) IsPaused() bool {
+	return len(r.Pauses) > 0 && r.Pauses[len(r.Pauses)-1].End == nil
+}
+
+// endOrNow returns the end time of the record or the current time if the
+// record is still being tracked.
+func (r *Record) endOrNow() time.Time {
+	if r.End != nil {
+		return *r.End
+	}
+
+	return time.Now()
+}
+
+// inLocation returns a copy of the record with all times converted to the given
+// location.
+func (r *Record) inLocation(loc *time.Location) Record {
+	converted := *r
+	converted.Start = r.Start.In(loc)
+
 	if r.End != nil {
-		return r.End.Sub(r.Start)
+		end := r.End.In(loc)
+		converted.End = &end
 	}
 
-	return time.Since(r.Start)
+	if r.Pauses != nil {
+		converted.Pauses = make([]Pause, len(r.Pauses))
+		for i, pause := range r.Pauses {
+			converted.Pauses[i].Start = pause.Start.In(loc)
+			if pause.End != nil {
+				end := pause.End.In(loc)
+				converted.Pauses[i].End = &end
+			}
+		}
+	}
+
+	return converted
+}
+
+// lastActivity returns the start time of the record or the start or end time of
+// its last pause, whichever is the latest. The record can't end before that.
+func (r *Record) lastActivity() time.Time {
+	if len(r.Pauses) == 0 {
+		return r.Start
+	}
+
+	pause := r.Pauses[len(r.Pauses)-1]
+	if pause.End != nil {
+		return *pause.End
+	}
+
+	return pause.Start
+}
+
+// hasPausesOutside checks if any of the record's pauses starts before the
+// record or ends after it.
+func (r *Record) hasPausesOutside() bool {
+	if len(r.Pauses) == 0 {
+		return false
+	}
+
+	if r.Pauses[0].Start.Before(r.Start) {
+		return true
+	}
+
+	return r.End != nil && r.lastActivity().After(*r.End)
+}
+
+// activeIntervals returns the start and end times of all intervals in which the
+// record was active, i.e. the time between the start and end of the record
+// without its pauses.
+func (r *Record) activeIntervals() [][2]time.Time {
+	var intervals [][2]time.Time
+
+	start := r.Start
+	end := r.endOrNow()
+
+	for _, pause := range r.Pauses {
+		intervals = append(intervals, [2]time.Time{start, pause.Start})
+
+		if pause.End == nil {
+			return intervals
+		}
+		start = *pause.End
+	}
+
+	return append(intervals, [2]time.Time{start, end})
 }
 
 // LoadRecord loads the record with the given start time. Returns
 // ErrRecordNotFound if the record cannot be found.
 func (t *Timetrace) LoadRecord(start time.Time) (*Record, error) {
-	path := t.fs.RecordFilepath(start)
-	return t.loadRecord(path)
+	data, err := t.fs.LoadRecord(start)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrRecordNotFound
+	}
+	return t.loadRecord(data, err)
 }
 
 func (t *Timetrace) LoadBackupRecord(start time.Time) (*Record, error) {
-	path := t.fs.RecordBackupFilepath(start)
-	return t.loadRecord(path)
+	data, err := t.fs.LoadRecordBackup(start)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBackupRecordNotFound
+	}
+	return t.loadRecord(data, err)
 }
 
 // ListRecords loads and returns all records from the given date. If no records
 // are found, an empty slice and no error will be returned.
+//
+// Records that started the day before and are still active after midnight are
+// included as well, since a part of them belongs to the given date. Their
+// duration on that date can be calculated using Record.DurationOn.
 func (t *Timetrace) ListRecords(date time.Time) ([]*Record, error) {
-	return t.loadAllRecords(date)
+	return t.loadRecordsOverlapping(dayRange(date))
 }
 
 // SaveRecord persists the given record. Returns ErrRecordAlreadyExists if the
 // record already exists and saving isn't forced.
 func (t *Timetrace) SaveRecord(record Record, force bool) error {
-	path := t.fs.RecordFilepath(record.Start)
+	// Records are stored with the offset of the home time zone, so that they
+	// are filed under the same day no matter where they have been created.
+	record = record.inLocation(t.formatter.Location())
 
-	if _, err := os.Stat(path); err == nil && !force {
+	if _, err := t.fs.LoadRecord(record.Start); err == nil && !force {
 		return ErrRecordAlreadyExists
 	}
 
-	if err := t.fs.EnsureRecordDir(record.Start); err != nil {
+	bytes, err := json.MarshalIndent(&record, "", "\t")
+	if err != nil {
 		return err
 	}
 
-	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	return t.fs.SaveRecord(record.Start, bytes)
+}
+
+// BackupRecord creates a backup of the given record file
+func (t *Timetrace) BackupRecord(recordKey time.Time) error {
+	record, err := t.LoadRecord(recordKey)
 	if err != nil {
 		return err
 	}
@@ -81,22 +318,21 @@ func (t *Timetrace) SaveRecord(record Record, force bool) error {
 		return err
 	}
 
-	_, err = file.Write(bytes)
-
-	return err
+	return t.fs.SaveRecordBackup(recordKey, bytes)
 }
 
-// BackupRecord creates a backup of the given record file
-func (t *Timetrace) BackupRecord(recordKey time.Time) error {
-	path := t.fs.RecordFilepath(recordKey)
-	record, err := t.loadRecord(path)
-	if err != nil {
+// RevertRecord restores the backup of the given record. Returns ErrRecordLocked
+// if the current record is locked and reverting isn't forced.
+//
+// If the record has been moved to the given key by changing its start time, it
+// is moved back to the key of the backup. Returns ErrRecordAlreadyExists if that
+// key is taken by another record.
+func (t *Timetrace) RevertRecord(recordKey time.Time, force bool) error {
+	if err := t.assertUnlocked(recordKey, force); err != nil {
 		return err
 	}
-	// create a new .bak filepath from the record struct
-	backupPath := t.fs.RecordBackupFilepath(recordKey)
 
-	backupFile, err := os.OpenFile(backupPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	record, err := t.LoadBackupRecord(recordKey)
 	if err != nil {
 		return err
 	}
@@ -106,36 +342,29 @@ func (t *Timetrace) BackupRecord(recordKey time.Time) error {
 		return err
 	}
 
-	_, err = backupFile.Write(bytes)
-
-	return err
-}
-
-func (t *Timetrace) RevertRecord(recordKey time.Time) error {
-	record, err := t.LoadBackupRecord(recordKey)
-	if err != nil {
-		return err
+	if sameRecordKey(record.Start, recordKey) {
+		return t.fs.SaveRecord(recordKey, bytes)
 	}
 
-	path := t.fs.RecordFilepath(recordKey)
-
-	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		return err
+	if _, err := t.fs.LoadRecord(record.Start); err == nil {
+		return ErrRecordAlreadyExists
 	}
 
-	bytes, err := json.MarshalIndent(&record, "", "\t")
-	if err != nil {
+	if err := t.fs.SaveRecord(record.Start, bytes); err != nil {
 		return err
 	}
 
-	_, err = file.Write(bytes)
+	err = t.fs.DeleteRecord(recordKey)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
 
 	return err
 }
 
 // RevertRecordsByProject is a function called if user opts to also revert records when they revert a project.
-func (t *Timetrace) RevertRecordsByProject(key string) error {
+// If any of the records is locked and reverting isn't forced, no record is reverted.
+func (t *Timetrace) RevertRecordsByProject(key string, force bool) error {
 	keys := make([]string, 0)
 
 	// check if project has submodules
@@ -154,47 +383,49 @@ func (t *Timetrace) RevertRecordsByProject(key string) error {
 	// append parent project key
 	keys = append(keys, key)
 
-	// get all record dirs and filepaths in order to load the record for matching the parent key
-	allRecordDirs, err := t.fs.RecordDirs()
+	// load all backup records in order to find the records matching the parent key
+	records, err := t.loadBackupRecords(time.Time{}, time.Time{})
 	if err != nil {
 		return err
 	}
+	records = filterRecordsByProjectKeys(records, keys)
 
-	records := make([]*Record, 0)
-	for _, recordDir := range allRecordDirs {
-		r, err := t.loadBackupsFromRecordDir(recordDir)
-		if err != nil {
-			return err
-		}
-		records = append(records, r...)
-	}
-	// check for records that match project key and revert record
-	for _, k := range keys {
+	if !force {
 		for _, record := range records {
-			if record.Project.Key != k {
-				continue
-			}
-			if err := t.RevertRecord(record.Start); err != nil {
+			if err := t.assertUnlocked(record.Start, false); err != nil {
 				return err
 			}
 		}
 	}
+
+	// revert all records matching the project key
+	for _, record := range records {
+		if err := t.RevertRecord(record.Start, true); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // DeleteRecord removes the given record. Returns ErrRecordNotFound if the
-// project doesn't exist.
-func (t *Timetrace) DeleteRecord(record Record) error {
-	path := t.fs.RecordFilepath(record.Start)
+// project doesn't exist and ErrRecordLocked if the record is locked and
+// deleting isn't forced.
+func (t *Timetrace) DeleteRecord(record Record, force bool) error {
+	if err := t.assertUnlocked(record.Start, force); err != nil {
+		return err
+	}
 
-	if _, err := os.Stat(path); os.IsNotExist(err) {
+	err := t.fs.DeleteRecord(record.Start)
+	if errors.Is(err, os.ErrNotExist) {
 		return ErrRecordNotFound
 	}
 
-	return os.Remove(path)
+	return err
 }
 
-func (t *Timetrace) DeleteRecordsByProject(key string) error {
+// DeleteRecordsByProject deletes all records of the project and its modules. If
+// any of the records is locked and deleting isn't forced, no record is deleted.
+func (t *Timetrace) DeleteRecordsByProject(key string, force bool) error {
 	keys := make([]string, 0)
 
 	// check if project has submodules
@@ -215,214 +446,384 @@ func (t *Timetrace) DeleteRecordsByProject(key string) error {
 	// append parent project key
 	keys = append(keys, key)
 
-	// get all record dirs and filepaths in order to load the record for matching the parent key
-	allRecordDirs, err := t.fs.RecordDirs()
+	// load all records in order to find the records matching the parent key
+	records, err := t.loadRecords(time.Time{}, time.Time{})
 	if err != nil {
 		return err
 	}
+	records = filterRecordsByProjectKeys(records, keys)
 
-	records := make([]*Record, 0)
-	for _, recordDir := range allRecordDirs {
-		r, err := t.loadFromRecordDir(recordDir)
-		if err != nil {
-			return err
-		}
-		records = append(records, r...)
-	}
-	// check for records that match project key and delete record
-	for _, k := range keys {
+	if !force {
 		for _, record := range records {
-			if record.Project.Key != k {
-				continue
+			if record.IsLocked() {
+				return ErrRecordLocked
 			}
-			if err := t.BackupRecord(record.Start); err != nil {
-				return err
-			}
-			t.DeleteRecord(*record)
+		}
+	}
+
+	// back up and delete all records matching the project key
+	for _, record := range records {
+		if err := t.BackupRecord(record.Start); err != nil {
+			return err
+		}
+		if err := t.DeleteRecord(*record, true); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-// EditRecordManual opens the record file in the preferred or default editor.
-func (t *Timetrace) EditRecordManual(recordTime time.Time) error {
-	path := t.fs.RecordFilepath(recordTime)
+// EditRecordManual opens the record in the preferred or default editor. Returns
+// ErrRecordLocked if the record is locked and editing isn't forced, and
+// ErrEditConflict if the record has been changed by another process in the
+// meantime.
+func (t *Timetrace) EditRecordManual(recordTime time.Time, force bool) error {
+	if err := t.assertUnlocked(recordTime, force); err != nil {
+		return err
+	}
+
+	data, err := t.fs.LoadRecord(recordTime)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrRecordNotFound
+	} else if err != nil {
+		return err
+	}
 
-	if _, err := t.loadRecord(path); err != nil {
+	var record *Record
+
+	_, err = t.editInEditor(data, func(data []byte) (err error) {
+		record, err = t.validateEditedRecord(recordTime, data)
+		return err
+	})
+	if err != nil {
 		return err
 	}
 
-	editor := t.editorFromEnvironment()
-	cmd := exec.Command(editor, path)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	// The store isn't locked while the editor is open, see runEditor.
+	if current, err := t.fs.LoadRecord(recordTime); err != nil || !bytes.Equal(current, data) {
+		return ErrEditConflict
+	}
 
-	return cmd.Run()
+	return t.SaveRecord(*record, true)
 }
 
-// EditRecord loads the record internally, applies the option values and saves the record
-func (t *Timetrace) EditRecord(recordTime time.Time, plus string, minus string) error {
-	path := t.fs.RecordFilepath(recordTime)
+// validateEditedRecord checks if the data edited by the user is a valid record
+// that can be stored under the given key and returns the record. The start time
+// can't be changed in the editor because it determines the key, use EditRecord
+// instead.
+func (t *Timetrace) validateEditedRecord(recordTime time.Time, data []byte) (*Record, error) {
+	var record Record
+
+	if err := decodeStrict(data, &record); err != nil {
+		return nil, fmt.Errorf("invalid record: %s", err)
+	}
+
+	if !sameRecordKey(record.Start, recordTime) {
+		return nil, fmt.Errorf("start time %s doesn't match the record key %s, use the --start flag to change it",
+			record.Start.Format(defaultRecordKeyLayout), recordTime.Format(defaultRecordKeyLayout))
+	}
+
+	if record.End != nil && record.End.Before(record.Start) {
+		return nil, errors.New("end time is before start time of record")
+	}
+
+	if record.Project == nil {
+		return nil, errors.New("record has no project")
+	}
+
+	if _, err := t.LoadProject(record.Project.Key); err != nil {
+		return nil, fmt.Errorf("%s: %s", err, record.Project.Key)
+	}
+
+	if record.hasPausesOutside() {
+		return nil, ErrPauseOutsideRecord
+	}
+
+	if err := t.ValidateTags(record.Tags); err != nil {
+		return nil, err
+	}
+
+	if err := t.assertNoCollisions(record, recordTime); err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// RecordChanges describes the changes applied to a record by EditRecord. Nil
+// and empty values leave the respective field of the record unchanged.
+type RecordChanges struct {
+	// Plus and Minus shift the end time of the record by a duration like
+	// "1h15m". They are applied after End.
+	Plus  string
+	Minus string
+	Start *time.Time
+	End   *time.Time
+	// ProjectKey moves the record to another existing project.
+	ProjectKey *string
+	AddTags    []string
+	RemoveTags []string
+	IsBillable *bool
+	Note       *string
+}
 
-	record, err := t.loadRecord(path)
+// EditRecord loads the record internally, applies the changes and saves the record.
+// Returns ErrRecordLocked if the record is locked and editing isn't forced.
+//
+// If the start or end time changes, the record must not collide with other records,
+// otherwise an error wrapping ErrRecordCollides is returned. Since records are keyed
+// by their start time, changing the start time moves the record to a new key. The
+// record under the old key is removed and its backup is copied to the new key, so
+// that RevertRecord restores the record under its old key. The new start and end
+// time must not cut off any pauses, otherwise ErrPauseOutsideRecord is returned.
+func (t *Timetrace) EditRecord(recordTime time.Time, changes RecordChanges, force bool) error {
+	record, err := t.LoadRecord(recordTime)
 	if err != nil {
 		return err
 	}
 
-	err = t.editRecord(record, plus, minus)
+	if record.IsLocked() && !force {
+		return ErrRecordLocked
+	}
+
+	oldStart, oldEnd := record.Start, record.End
+
+	err = t.editRecord(record, changes)
 	if err != nil {
 		return err
 	}
 
-	err = t.SaveRecord(*record, true)
+	timeChanged := !record.Start.Equal(oldStart) || (record.End != nil) != (oldEnd != nil) ||
+		(record.End != nil && !record.End.Equal(*oldEnd))
+
+	if timeChanged {
+		if record.hasPausesOutside() {
+			return ErrPauseOutsideRecord
+		}
+		if err := t.assertNoCollisions(*record, recordTime); err != nil {
+			return err
+		}
+	}
+
+	if sameRecordKey(record.Start, recordTime) {
+		return t.SaveRecord(*record, true)
+	}
+
+	// The start time has moved to another minute, so the record is stored
+	// under a new key.
+	if err := t.SaveRecord(*record, false); err != nil {
+		return err
+	}
+
+	backup, err := t.fs.LoadRecordBackup(recordTime)
+	if err == nil {
+		err = t.fs.SaveRecordBackup(record.Start, backup)
+	} else if errors.Is(err, os.ErrNotExist) {
+		err = nil
+	}
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return t.DeleteRecord(Record{Start: recordTime}, true)
 }
 
-func (t *Timetrace) loadAllRecords(date time.Time) ([]*Record, error) {
-	dir := t.fs.RecordDirFromDate(date)
+// LoadRecordByID loads a record by its ID as returned by RecordID. An ID has
+// the form <DATE>@<N>, where N numbers the records that started on the given
+// date, starting with the oldest as 1. The date can be any date understood by
+// Formatter.ParseDate and defaults to today, so @2 is the second record from
+// today. Returns ErrRecordNotFound if there is no such record.
+func (t *Timetrace) LoadRecordByID(id string) (*Record, error) {
+	i := strings.LastIndex(id, recordIDSeparator)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRecordID, id)
+	}
 
-	recordFilepaths, err := t.fs.RecordFilepaths(dir, func(_, _ string) bool {
-		return true
-	})
+	date := t.formatter.Now()
+	if datePart := id[:i]; datePart != "" {
+		var err error
+		if date, err = t.formatter.ParseDate(datePart); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidRecordID, id)
+		}
+	}
+
+	n, err := strconv.Atoi(id[i+1:])
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRecordID, id)
+	}
+
+	keys, err := t.fs.RecordKeys(dayRange(date))
 	if err != nil {
 		return nil, err
 	}
 
-	var records []*Record
+	if n > len(keys) {
+		return nil, fmt.Errorf("%w: %s", ErrRecordNotFound, id)
+	}
 
-	for _, recordFilepath := range recordFilepaths {
-		record, err := t.loadRecord(recordFilepath)
-		if err != nil {
-			return nil, err
-		}
-		records = append(records, record)
+	return t.LoadRecord(keys[n-1])
+}
+
+// RecordID returns the ID of the given record, which can be passed to
+// LoadRecordByID. The ID stays the same as long as no older record from the
+// same day is added or removed.
+func (t *Timetrace) RecordID(record *Record) (string, error) {
+	ids, err := t.RecordIDs(record.Start)
+	if err != nil {
+		return "", err
 	}
 
-	return records, nil
+	id, ok := ids[t.formatter.RecordKey(record)]
+	if !ok {
+		return "", ErrRecordNotFound
+	}
+
+	return id, nil
 }
 
-func (t *Timetrace) loadAllRecordsSortedAscending(date time.Time) ([]*Record, error) {
-	dir := t.fs.RecordDirFromDate(date)
+// RecordIDs returns the IDs of all records that started on the given date in
+// the home time zone, keyed by their record key as returned by
+// Formatter.RecordKey. The record keys of that date are only read once, so
+// this should be preferred over RecordID for multiple records.
+func (t *Timetrace) RecordIDs(date time.Time) (map[string]string, error) {
+	date = date.In(t.formatter.Location())
 
-	recordFilepaths, err := t.fs.RecordFilepaths(dir, func(a, b string) bool {
-		timeA, _ := time.Parse(recordLayout, a)
-		timeB, _ := time.Parse(recordLayout, b)
-		return timeA.Before(timeB)
-	})
+	keys, err := t.fs.RecordKeys(dayRange(date))
 	if err != nil {
 		return nil, err
 	}
 
-	var records []*Record
+	ids := make(map[string]string, len(keys))
 
-	for _, recordFilepath := range recordFilepaths {
-		record, err := t.loadRecord(recordFilepath)
-		if err != nil {
-			return nil, err
-		}
-		records = append(records, record)
+	for i, key := range keys {
+		ids[t.formatter.RecordKey(&Record{Start: key})] = date.Format(dateLayout) + recordIDSeparator + strconv.Itoa(i+1)
 	}
 
-	return records, nil
+	return ids, nil
 }
 
-// LoadRecordByID loads a record of the current day by the ID
-// provided in list records (starting with the oldest as #1)
-func (t *Timetrace) LoadRecordByID(ID int) (*Record, error) {
-	recs, err := t.loadAllRecordsSortedAscending(time.Now())
+// LoadRunningRecords loads all records that haven't been stopped yet, sorted
+// from oldest to newest. If no record is running, an empty slice and no error
+// will be returned.
+//
+// Since only parallel records can be started while other records are running,
+// the search stops at the youngest record that isn't a parallel record.
+func (t *Timetrace) LoadRunningRecords() ([]*Record, error) {
+	runningRecords := make([]*Record, 0)
+
+	latestRecord, err := t.LoadLatestRecord()
+	if err != nil || latestRecord == nil {
+		return runningRecords, err
+	}
+
+	if !latestRecord.IsParallel {
+		if latestRecord.End == nil {
+			runningRecords = append(runningRecords, latestRecord)
+		}
+		return runningRecords, nil
+	}
 
+	keys, err := t.fs.RecordKeys(time.Time{}, time.Time{})
 	if err != nil {
 		return nil, err
 	}
 
-	if len(recs) < ID || ID == 0 {
-		return nil, nil
+	for i := len(keys) - 1; i >= 0; i-- {
+		record, err := t.LoadRecord(keys[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if record.End == nil {
+			runningRecords = append([]*Record{record}, runningRecords...)
+		}
+
+		if !record.IsParallel {
+			break
+		}
 	}
 
-	return recs[ID-1], nil
+	return runningRecords, nil
 }
 
 // LoadLatestRecord loads the youngest record. This may also be a record from
 // another day. If there is no latest record, nil and no error will be returned.
 func (t *Timetrace) LoadLatestRecord() (*Record, error) {
-	latestDirs, err := t.fs.RecordDirs()
+	start, err := t.fs.LatestRecordKey()
 	if err != nil {
 		return nil, err
 	}
 
-	if len(latestDirs) == 0 {
+	if start.IsZero() {
 		return nil, nil
 	}
 
-	dir, err := t.latestNonEmptyDir(latestDirs)
+	return t.LoadRecord(start)
+}
+
+// loadRecordsOverlapping loads all records that have been active within the
+// given range, sorted from oldest to newest. Since records are stored by their
+// start time, records that started the day before the range and running
+// records are considered as well. Records spanning more than one midnight are
+// only found if they're still running, which is why they can be split at
+// midnight when stopping them.
+func (t *Timetrace) loadRecordsOverlapping(from, to time.Time) ([]*Record, error) {
+	dayBefore, _ := dayRange(from.AddDate(0, 0, -1))
+
+	records, err := t.loadRecords(dayBefore, to, func(r *Record) bool {
+		return r.Start.Before(to) && r.endOrNow().After(from)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	latestRecords, err := t.fs.RecordFilepaths(dir, func(a, b string) bool {
-		timeA, _ := time.Parse(recordLayout, a)
-		timeB, _ := time.Parse(recordLayout, b)
-		return timeA.Before(timeB)
-	})
+	runningRecords, err := t.LoadRunningRecords()
 	if err != nil {
 		return nil, err
 	}
 
-	if len(latestRecords) == 0 {
-		return nil, nil
-	}
+	var olderRecords []*Record
 
-	path := latestRecords[len(latestRecords)-1]
+	for _, record := range runningRecords {
+		if record.Start.Before(dayBefore) {
+			olderRecords = append(olderRecords, record)
+		}
+	}
 
-	return t.loadRecord(path)
+	return append(olderRecords, records...), nil
 }
 
-// loadOldestRecord returns the oldest record of the given date. If there is no
-// oldest record, nil and no error will be returned.
-func (t *Timetrace) loadOldestRecord(date time.Time) (*Record, error) {
-	dir := t.fs.RecordDirFromDate(date)
-
-	oldestRecords, err := t.fs.RecordFilepaths(dir, func(a, b string) bool {
-		timeA, _ := time.Parse(recordLayout, a)
-		timeB, _ := time.Parse(recordLayout, b)
-		return timeA.After(timeB)
-	})
+// loadRecords loads all records that started within the given range, sorted
+// from oldest to newest. A zero time disables the respective boundary. The
+// slice can be filtered through the filter options.
+// !imporant: backup records will be ignored by this function!
+func (t *Timetrace) loadRecords(from, to time.Time, filter ...func(*Record) bool) ([]*Record, error) {
+	keys, err := t.fs.RecordKeys(from, to)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(oldestRecords) == 0 {
-		return nil, nil
-	}
-
-	path := oldestRecords[0]
-
-	return t.loadRecord(path)
+	return t.loadRecordsByKeys(keys, t.LoadRecord, filter...)
 }
 
-// loadFromRecordDir loads all records for one directory and returns them. The slice can be filtered
-// through the filter options.
-// !imporant: .bak files will be ignored by this function - only .json files in the directory will be read!
-func (t *Timetrace) loadFromRecordDir(recordDir string, filter ...func(*Record) bool) ([]*Record, error) {
-	filesInfo, err := ioutil.ReadDir(recordDir)
+// loadBackupRecords loads all backup records that started within the given
+// range. The slice can be filtered through the filter options.
+func (t *Timetrace) loadBackupRecords(from, to time.Time, filter ...func(*Record) bool) ([]*Record, error) {
+	keys, err := t.fs.RecordBackupKeys(from, to)
 	if err != nil {
 		return nil, err
 	}
+
+	return t.loadRecordsByKeys(keys, t.LoadBackupRecord, filter...)
+}
+
+func (t *Timetrace) loadRecordsByKeys(keys []time.Time, load func(time.Time) (*Record, error), filter ...func(*Record) bool) ([]*Record, error) {
 	var foundRecords = make([]*Record, 0)
 
 outer:
-	for _, info := range filesInfo {
-		// igonre backup file
-		if isBakFile(info.Name()) {
-			continue
-		}
-		record, err := t.loadRecord(filepath.Join(recordDir, info.Name()))
+	for _, key := range keys {
+		record, err := load(key)
 		if err != nil {
 			return nil, err
 		}
@@ -439,61 +840,206 @@ outer:
 	return foundRecords, nil
 }
 
-// loadBackupsFromRecordDir loads all records for one directory and returns them. The slice can be filtered
-// through the filter options.
-func (t *Timetrace) loadBackupsFromRecordDir(recordDir string, filter ...func(*Record) bool) ([]*Record, error) {
-	filesInfo, err := ioutil.ReadDir(recordDir)
+// loadRecord decodes the record data returned by the storage backend. The
+// error returned along with the data is passed through.
+func (t *Timetrace) loadRecord(data []byte, err error) (*Record, error) {
 	if err != nil {
 		return nil, err
 	}
-	var foundRecords = make([]*Record, 0)
 
-outer:
-	for _, info := range filesInfo {
-		// get only backup files
-		if !isBakFile(info.Name()) {
-			continue
-		}
+	var record Record
 
-		record, err := t.loadRecord(filepath.Join(recordDir, info.Name()))
-		if err != nil {
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+
+	// Records created in another time zone are converted to the home time
+	// zone, so that they are split and grouped by the right days.
+	record = record.inLocation(t.formatter.Location())
+
+	return &record, nil
+}
+
+// LockRecords locks all finished records that started within the given time
+// range, which works just like the one of FilterByTimeRange. If projectKey
+// isn't empty, only the records of that project and its modules are locked.
+// Setting locked to false unlocks the records instead. Records that have been
+// invoiced remain locked. The changed records are returned.
+func (t *Timetrace) LockRecords(from, to time.Time, projectKey string, locked bool) ([]*Record, error) {
+	filter := []func(*Record) bool{
+		FilterNoneNilEndTime,
+		FilterByTimeRange(from, to),
+		func(r *Record) bool {
+			return r.Locked != locked
+		},
+	}
+
+	if projectKey != "" {
+		filter = append(filter, FilterByProject(projectKey))
+	}
+
+	loadFrom, loadTo := reportRange(from, to)
+
+	records, err := t.loadRecords(loadFrom, loadTo, filter...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		record.Locked = locked
+
+		if err := t.SaveRecord(*record, true); err != nil {
 			return nil, err
 		}
-		// apply all filter on record to check if Records should be used
-		for _, f := range filter {
-			if !f(record) {
-				// if either filter returns false
-				// skip record
-				continue outer
+	}
+
+	return records, nil
+}
+
+// assertUnlocked returns ErrRecordLocked if the record with the given start
+// time is locked and force isn't set. A record that doesn't exist isn't locked.
+func (t *Timetrace) assertUnlocked(recordKey time.Time, force bool) error {
+	if force {
+		return nil
+	}
+
+	record, err := t.LoadRecord(recordKey)
+	if errors.Is(err, ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if record.IsLocked() {
+		return ErrRecordLocked
+	}
+
+	return nil
+}
+
+// filterRecordsByProjectKeys returns all records belonging to one of the given
+// project keys, grouped in the order of the keys.
+func filterRecordsByProjectKeys(records []*Record, keys []string) []*Record {
+	filtered := make([]*Record, 0)
+
+	for _, key := range keys {
+		for _, record := range records {
+			if record.Project.Key == key {
+				filtered = append(filtered, record)
 			}
 		}
-		foundRecords = append(foundRecords, record)
 	}
-	return foundRecords, nil
+
+	return filtered
 }
 
-func (t *Timetrace) loadRecord(path string) (*Record, error) {
-	file, err := ioutil.ReadFile(path)
+// dayRange returns the start of the given date and the start of the following
+// date in the date's own time zone, which is the home time zone for dates
+// parsed by the Formatter and for loaded records.
+func dayRange(date time.Time) (time.Time, time.Time) {
+	year, month, day := date.Date()
+	from := time.Date(year, month, day, 0, 0, 0, 0, date.Location())
+	return from, from.AddDate(0, 0, 1)
+}
+
+// assertNoCollisions returns an error wrapping ErrRecordCollides if the record
+// collides with any other record. The record stored under recordKey is the
+// record itself before editing, so it is ignored. A running record is checked
+// up to the current time.
+//
+// Running records are only searched back to the latest non-parallel record, so
+// a running parallel record must not start before it. Otherwise, an error
+// wrapping ErrParallelTooEarly is returned.
+func (t *Timetrace) assertNoCollisions(record Record, recordKey time.Time) error {
+	if record.IsParallel && record.End == nil {
+		latest, err := t.previousRecord(time.Time{})
+		if err != nil {
+			return err
+		}
+
+		if latest != nil && !sameRecordKey(latest.Start, recordKey) && !latest.Start.Before(record.Start) {
+			return fmt.Errorf("%w: %s", ErrParallelTooEarly, t.formatter.RecordKey(latest))
+		}
+	}
+
+	if record.End == nil {
+		now := t.formatter.Now()
+		record.End = &now
+	}
+
+	_, collidingRecords, err := t.recordCollides(record)
 	if err != nil {
-		if os.IsNotExist(err) {
-			if strings.HasSuffix(path, ".bak") {
-				return nil, ErrBackupRecordNotFound
+		return err
+	}
+
+	var keys []string
+
+	for _, colliding := range collidingRecords {
+		if sameRecordKey(colliding.Start, recordKey) {
+			continue
+		}
+		keys = append(keys, t.Formatter().RecordKey(colliding))
+	}
+
+	if len(keys) > 0 {
+		return fmt.Errorf("%w: %s", ErrRecordCollides, strings.Join(keys, ", "))
+	}
+
+	return nil
+}
+
+func (t *Timetrace) editRecord(record *Record, changes RecordChanges) error {
+	if changes.Note != nil {
+		record.Note = *changes.Note
+	}
+
+	if changes.IsBillable != nil {
+		record.IsBillable = *changes.IsBillable
+	}
+
+	if changes.ProjectKey != nil {
+		project, err := t.LoadProject(*changes.ProjectKey)
+		if err != nil {
+			return err
+		}
+		record.Project = project
+	}
+
+	if len(changes.AddTags) > 0 {
+		if err := t.ValidateTags(changes.AddTags); err != nil {
+			return err
+		}
+		record.Tags = uniqueTags(append(record.Tags, changes.AddTags...))
+	}
+
+	if len(changes.RemoveTags) > 0 {
+		tags := make([]string, 0, len(record.Tags))
+		for _, tag := range record.Tags {
+			if !containsTag(changes.RemoveTags, tag) {
+				tags = append(tags, tag)
 			}
-			return nil, ErrRecordNotFound
 		}
-		return nil, err
+		record.Tags = tags
 	}
 
-	var record Record
+	if changes.Start != nil {
+		record.Start = *changes.Start
+	}
 
-	if err := json.Unmarshal(file, &record); err != nil {
-		return nil, err
+	if changes.End != nil {
+		end := *changes.End
+		record.End = &end
 	}
 
-	return &record, nil
-}
+	if record.End != nil && record.End.Before(record.Start) {
+		return errors.New("end time is before start time of record")
+	}
 
-func (t *Timetrace) editRecord(record *Record, plus string, minus string) error {
+	plus, minus := changes.Plus, changes.Minus
+
+	if plus == "" && minus == "" {
+		return nil
+	}
 
 	if record.End == nil {
 		return errors.New("record is still in progress")
@@ -519,3 +1065,18 @@ func (t *Timetrace) editRecord(record *Record, plus string, minus string) error
 
 	return nil
 }
+
+// sameRecordKey checks if two start times result in the same record key. Keys
+// have minute precision, so seconds are ignored.
+func sameRecordKey(a, b time.Time) bool {
+	return a.Truncate(time.Minute).Equal(b.Truncate(time.Minute))
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,384 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dominikbraun/timetrace/config"
+)
+
+func TestRecordDuration(t *testing.T) {
+	start := time.Date(2021, 06, 07, 16, 00, 00, 00, time.Local)
+	at := func(minutes int) *time.Time {
+		t := start.Add(time.Duration(minutes) * time.Minute)
+		return &t
+	}
+
+	tests := map[string]struct {
+		record   Record
+		duration time.Duration
+		paused   bool
+	}{
+		"no pauses": {
+			record:   Record{Start: start, End: at(60)},
+			duration: 60 * time.Minute,
+		},
+		"one finished pause": {
+			record: Record{Start: start, End: at(60), Pauses: []Pause{
+				{Start: *at(10), End: at(25)},
+			}},
+			duration: 45 * time.Minute,
+		},
+		"two finished pauses": {
+			record: Record{Start: start, End: at(60), Pauses: []Pause{
+				{Start: *at(10), End: at(25)},
+				{Start: *at(30), End: at(35)},
+			}},
+			duration: 40 * time.Minute,
+		},
+		"active pause of stopped record": {
+			record: Record{Start: start, End: at(60), Pauses: []Pause{
+				{Start: *at(50)},
+			}},
+			duration: 50 * time.Minute,
+			paused:   true,
+		},
+	}
+
+	for name, tc := range tests {
+		if duration := tc.record.Duration(); duration != tc.duration {
+			t.Errorf("%s: expected duration %v, got %v", name, tc.duration, duration)
+		}
+		if paused := tc.record.IsPaused(); paused != tc.paused {
+			t.Errorf("%s: expected paused to be %v, got %v", name, tc.paused, paused)
+		}
+	}
+}
+
+func TestRecordIsLocked(t *testing.T) {
+	tests := map[string]struct {
+		record Record
+		locked bool
+	}{
+		"unlocked":          {record: Record{}, locked: false},
+		"locked explicitly": {record: Record{Locked: true}, locked: true},
+		"invoiced":          {record: Record{Invoice: "2021-001"}, locked: true},
+	}
+
+	for name, tc := range tests {
+		if locked := tc.record.IsLocked(); locked != tc.locked {
+			t.Errorf("%s: expected locked to be %v, got %v", name, tc.locked, locked)
+		}
+	}
+}
+
+func TestEditRecord(t *testing.T) {
+	start := time.Date(2021, 06, 07, 16, 00, 00, 00, time.Local)
+	at := func(minutes int) *time.Time {
+		t := start.Add(time.Duration(minutes) * time.Minute)
+		return &t
+	}
+	billable := true
+	note := "Descale the machine"
+	noNote := ""
+
+	tt := &Timetrace{config: &config.Config{}}
+
+	tests := map[string]struct {
+		changes     RecordChanges
+		expected    Record
+		expectedErr bool
+	}{
+		"add and remove tags": {
+			changes:  RecordChanges{AddTags: []string{"espresso", "coffee"}, RemoveTags: []string{"tea"}},
+			expected: Record{Start: start, End: at(60), Tags: []string{"coffee", "espresso"}},
+		},
+		"mark as billable": {
+			changes:  RecordChanges{IsBillable: &billable},
+			expected: Record{Start: start, End: at(60), Tags: []string{"coffee", "tea"}, IsBillable: true},
+		},
+		"set note": {
+			changes:  RecordChanges{Note: &note},
+			expected: Record{Start: start, End: at(60), Tags: []string{"coffee", "tea"}, Note: note},
+		},
+		"clear note": {
+			changes:  RecordChanges{Note: &noNote},
+			expected: Record{Start: start, End: at(60), Tags: []string{"coffee", "tea"}},
+		},
+		"move start and end": {
+			changes:  RecordChanges{Start: at(-30), End: at(30)},
+			expected: Record{Start: *at(-30), End: at(30), Tags: []string{"coffee", "tea"}},
+		},
+		"change end and add duration": {
+			changes:  RecordChanges{End: at(30), Plus: "15m"},
+			expected: Record{Start: start, End: at(45), Tags: []string{"coffee", "tea"}},
+		},
+		"start after end": {
+			changes:     RecordChanges{Start: at(90)},
+			expectedErr: true,
+		},
+		"invalid tag": {
+			changes:     RecordChanges{AddTags: []string{"!coffee"}},
+			expectedErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		record := Record{Start: start, End: at(60), Tags: []string{"coffee", "tea"}}
+		if tc.changes.Note != nil {
+			record.Note = "Grind the beans"
+		}
+
+		err := tt.editRecord(&record, tc.changes)
+		if tc.expectedErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+
+		if !reflect.DeepEqual(record, tc.expected) {
+			t.Errorf("%s: expected %+v, got %+v", name, tc.expected, record)
+		}
+	}
+}
+
+func TestValidateEditedRecord(t *testing.T) {
+	start := time.Date(2021, 06, 07, 16, 00, 00, 00, time.Local)
+	at := func(minutes int) *time.Time {
+		t := start.Add(time.Duration(minutes) * time.Minute)
+		return &t
+	}
+
+	tests := map[string]struct {
+		record      Record
+		trailing    string
+		expectedErr bool
+	}{
+		"valid record": {
+			record: Record{Start: start, End: at(60), Pauses: []Pause{{Start: *at(10), End: at(20)}}},
+		},
+		"trailing data": {
+			record:      Record{Start: start, End: at(60)},
+			trailing:    " }garbage",
+			expectedErr: true,
+		},
+		"pause outside record": {
+			record:      Record{Start: start, End: at(60), Pauses: []Pause{{Start: *at(70), End: at(80)}}},
+			expectedErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		tt := newMemoryTimetrace(t)
+
+		tc.record.Project = &Project{Key: "make-coffee"}
+
+		data, err := json.Marshal(&tc.record)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+
+		record, err := tt.validateEditedRecord(start, append(data, tc.trailing...))
+		if tc.expectedErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+
+		if !record.Start.Equal(start) || len(record.Pauses) != 1 {
+			t.Errorf("%s: unexpected record %+v", name, record)
+		}
+	}
+}
+
+func TestEditRecordStart(t *testing.T) {
+	start := time.Date(2021, 06, 07, 16, 00, 00, 00, time.Local)
+	at := func(minutes int) *time.Time {
+		t := start.Add(time.Duration(minutes) * time.Minute)
+		return &t
+	}
+
+	tt := newMemoryTimetrace(t)
+
+	record := Record{Start: start, End: at(60), Project: &Project{Key: "make-coffee"}, Pauses: []Pause{
+		{Start: *at(10), End: at(20)},
+	}}
+	if err := tt.SaveRecord(record, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for name, changes := range map[string]RecordChanges{
+		"start after pause": {Start: at(15)},
+		"end before pause":  {End: at(15)},
+	} {
+		if err := tt.EditRecord(start, changes, false); !errors.Is(err, ErrPauseOutsideRecord) {
+			t.Errorf("%s: expected error %v, got %v", name, ErrPauseOutsideRecord, err)
+		}
+	}
+
+	if err := tt.BackupRecord(start); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := tt.EditRecord(start, RecordChanges{Start: at(-30)}, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := tt.LoadRecord(start); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("expected the record to be moved, got %v", err)
+	}
+
+	// The backup has been moved along with the record, so reverting the edit
+	// moves the record back to its old key.
+	if err := tt.RevertRecord(*at(-30), false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := tt.LoadRecord(*at(-30)); !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("expected the moved record to be removed, got %v", err)
+	}
+
+	reverted, err := tt.LoadRecord(start)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reverted.Start.Equal(start) || !reverted.End.Equal(*at(60)) {
+		t.Errorf("expected the record to be reverted, got %+v", reverted)
+	}
+}
+
+func TestRecordSplitAtMidnight(t *testing.T) {
+	start := time.Date(2021, 06, 07, 22, 00, 00, 00, time.Local)
+	at := func(minutes int) *time.Time {
+		t := start.Add(time.Duration(minutes) * time.Minute)
+		return &t
+	}
+
+	tests := map[string]struct {
+		record    Record
+		durations []time.Duration
+		pauses    []int
+	}{
+		"same day": {
+			record:    Record{Start: start, End: at(60)},
+			durations: []time.Duration{60 * time.Minute},
+			pauses:    []int{0},
+		},
+		"overnight": {
+			record:    Record{Start: start, End: at(180)},
+			durations: []time.Duration{120 * time.Minute, 60 * time.Minute},
+			pauses:    []int{0, 0},
+		},
+		"two midnights": {
+			record:    Record{Start: start, End: at(1620)},
+			durations: []time.Duration{120 * time.Minute, 24 * time.Hour, 60 * time.Minute},
+			pauses:    []int{0, 0, 0},
+		},
+		"pause across midnight": {
+			record: Record{Start: start, End: at(180), Pauses: []Pause{
+				{Start: *at(90), End: at(150)},
+			}},
+			durations: []time.Duration{90 * time.Minute, 30 * time.Minute},
+			pauses:    []int{1, 1},
+		},
+	}
+
+	for name, tc := range tests {
+		parts := tc.record.splitAtMidnight()
+
+		if len(parts) != len(tc.durations) {
+			t.Fatalf("%s: expected %d parts, got %d", name, len(tc.durations), len(parts))
+		}
+
+		var total time.Duration
+
+		for i, part := range parts {
+			if duration := part.Duration(); duration != tc.durations[i] {
+				t.Errorf("%s: expected part %d to last %v, got %v", name, i, tc.durations[i], duration)
+			}
+			if len(part.Pauses) != tc.pauses[i] {
+				t.Errorf("%s: expected part %d to have %d pauses, got %d", name, i, tc.pauses[i], len(part.Pauses))
+			}
+			if duration := tc.record.DurationOn(part.Start); duration != tc.durations[i] {
+				t.Errorf("%s: expected %v on day %d, got %v", name, tc.durations[i], i, duration)
+			}
+			total += part.Duration()
+		}
+
+		if total != tc.record.Duration() {
+			t.Errorf("%s: expected parts to last %v in total, got %v", name, tc.record.Duration(), total)
+		}
+	}
+}
+
+func TestRecordID(t *testing.T) {
+	tt := newMemoryTimetrace(t)
+
+	starts := []time.Time{
+		time.Date(2021, 06, 06, 22, 00, 00, 00, time.Local),
+		time.Date(2021, 06, 07, 8, 00, 00, 00, time.Local),
+		time.Date(2021, 06, 07, 10, 00, 00, 00, time.Local),
+	}
+
+	for _, start := range starts {
+		end := start.Add(time.Hour)
+		if err := tt.SaveRecord(Record{Start: start, End: &end, Project: &Project{Key: "make-coffee"}}, false); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	for i, expected := range []string{"2021-06-06@1", "2021-06-07@1", "2021-06-07@2"} {
+		id, err := tt.RecordID(&Record{Start: starts[i]})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", expected, err)
+		}
+		if id != expected {
+			t.Errorf("expected ID %s, got %s", expected, id)
+		}
+
+		record, err := tt.LoadRecordByID(id)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", id, err)
+		}
+		if !record.Start.Equal(starts[i]) {
+			t.Errorf("%s: expected record %s, got %s", id, starts[i], record.Start)
+		}
+	}
+
+	ids, err := tt.RecordIDs(starts[1])
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expectedIDs := map[string]string{
+		tt.formatter.RecordKey(&Record{Start: starts[1]}): "2021-06-07@1",
+		tt.formatter.RecordKey(&Record{Start: starts[2]}): "2021-06-07@2",
+	}
+	if !reflect.DeepEqual(ids, expectedIDs) {
+		t.Errorf("expected IDs %v, got %v", expectedIDs, ids)
+	}
+
+	invalid := map[string]error{
+		"2021-06-07@3": ErrRecordNotFound,
+		"2021-06-07@0": ErrInvalidRecordID,
+		"someday@1":    ErrInvalidRecordID,
+		"2021-06-07":   ErrInvalidRecordID,
+	}
+
+	for id, expectedErr := range invalid {
+		if _, err := tt.LoadRecordByID(id); !errors.Is(err, expectedErr) {
+			t.Errorf("%s: expected error %v, got %v", id, expectedErr, err)
+		}
+	}
+}
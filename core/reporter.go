@@ -1,14 +1,18 @@
 package core
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 )
 
 const (
 	defaultTotalSymbol = "∑"
+	tagNegationPrefix  = "!"
 )
 
 func FilterNoneNilEndTime(r *Record) bool {
@@ -44,6 +48,50 @@ func FilterByProject(key string) func(*Record) bool {
 	}
 }
 
+// FilterByTag returns true if the record matches the given tags. Tags prefixed
+// with an exclamation mark are negated: the record must not have any of them.
+// If all is set, the record must have all of the remaining tags, otherwise it
+// must have at least one of them. If there are no remaining tags, only the
+// negated tags are checked.
+func FilterByTag(tags []string, all bool) func(*Record) bool {
+	var included, excluded []string
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, tagNegationPrefix) {
+			excluded = append(excluded, strings.TrimPrefix(tag, tagNegationPrefix))
+			continue
+		}
+		included = append(included, tag)
+	}
+
+	return func(r *Record) bool {
+		recordTags := make(map[string]bool)
+		for _, tag := range r.Tags {
+			recordTags[tag] = true
+		}
+
+		for _, tag := range excluded {
+			if recordTags[tag] {
+				return false
+			}
+		}
+
+		if len(included) == 0 {
+			return true
+		}
+
+		for _, tag := range included {
+			if recordTags[tag] && !all {
+				return true
+			}
+			if !recordTags[tag] && all {
+				return false
+			}
+		}
+
+		return all
+	}
+}
+
 // FilterByTimeRange allows to determine whether a given records is in-between a time-range.
 // If "to" is nil the upper boundary is ignored and vice versa with "from". If both are nil returns true
 // start and end time are both inclusive.
@@ -64,12 +112,13 @@ func FilterByTimeRange(start, end time.Time) func(*Record) bool {
 		if start.IsZero() {
 			// adding one day end the "end" date is required in or for
 			// the end-time to be inclusive
-			return r.Start.Unix() <= end.AddDate(0, 0, 1).Unix()
+			return r.Start.Unix() < end.AddDate(0, 0, 1).Unix()
 		}
 
 		// adding one day end the "end" date is required in or for
-		// the end-time to be inclusive
-		return r.Start.Unix() >= start.Unix() && r.Start.Unix() <= end.AddDate(0, 0, 1).Unix()
+		// the end-time to be inclusive. Records starting at midnight of
+		// the following day are excluded though.
+		return r.Start.Unix() >= start.Unix() && r.Start.Unix() < end.AddDate(0, 0, 1).Unix()
 	}
 }
 
@@ -80,6 +129,19 @@ type Reporter struct {
 	report map[string][]*Record
 	// total stores the overall time spend on a project
 	totals map[string]time.Duration
+	// rates stores project-key:hourly-rate for all projects of the records,
+	// including modules
+	rates map[string]hourlyRate
+	// amounts stores the billable amount of a project per currency
+	amounts map[string]map[string]float64
+	// tagTotals stores the overall time spent on a tag
+	tagTotals map[string]time.Duration
+}
+
+// hourlyRate is the rate billed per hour for a project.
+type hourlyRate struct {
+	rate     float64
+	currency string
 }
 
 // sortAndMerge assigns each record in the given slice to the correct project key in the
@@ -103,6 +165,51 @@ func (r *Reporter) sortAndMerge(records []*Record) {
 		tmp := r.totals[key] + record.Duration()
 		r.totals[key] = tmp
 	}
+
+	for _, record := range records {
+		for _, tag := range uniqueTags(record.Tags) {
+			r.tagTotals[tag] += record.Duration()
+		}
+	}
+
+	for _, record := range records {
+		amount, currency, ok := r.amount(record)
+		if !ok {
+			continue
+		}
+		key := record.Project.Key
+		if record.Project.IsModule() {
+			key = record.Project.Parent()
+		}
+		if _, ok := r.amounts[key]; !ok {
+			r.amounts[key] = make(map[string]float64)
+		}
+		r.amounts[key][currency] += amount
+	}
+}
+
+// amount returns the billable amount of the given record and its currency. If
+// the record isn't billable or there is no rate for its project, ok is false.
+func (r *Reporter) amount(record *Record) (amount float64, currency string, ok bool) {
+	rate, hasRate := r.rates[record.Project.Key]
+	if !record.IsBillable || !hasRate || rate.rate == 0 {
+		return 0, "", false
+	}
+
+	return record.Duration().Hours() * rate.rate, rate.currency, true
+}
+
+// TotalAmount returns the billable amount of all projects per currency.
+func (r Reporter) TotalAmount() map[string]float64 {
+	total := make(map[string]float64)
+
+	for _, amounts := range r.amounts {
+		for currency, amount := range amounts {
+			total[currency] += amount
+		}
+	}
+
+	return total
 }
 
 // Table prepares the r.report and r.totals data in a way that it can be consumed by the out.Table
@@ -128,28 +235,74 @@ func (r Reporter) Table() ([][]string, string) {
 			start := r.t.Formatter().TimeString(record.Start)
 			end := r.t.Formatter().TimeString(*record.End)
 
-			rows = append(rows, []string{key, module, date, start, end, billable, ""})
+			var amount string
+			if value, currency, ok := r.amount(record); ok {
+				amount = r.t.Formatter().FormatAmounts(map[string]float64{currency: value})
+			}
+
+			rows = append(rows, []string{key, module, date, start, end, billable, record.Note, "", amount})
 		}
 		// append with last row for total of tracked time for project
-		rows = append(rows, []string{"", "", "", "", "", defaultTotalSymbol, r.t.Formatter().FormatDuration(r.totals[key])})
+		rows = append(rows, []string{
+			"", "", "", "", "", "", defaultTotalSymbol,
+			r.t.Formatter().FormatDuration(r.totals[key]),
+			r.t.Formatter().FormatAmounts(r.amounts[key]),
+		})
 		totalSum += r.totals[key]
 	}
 	return rows, r.t.Formatter().FormatDuration(totalSum)
 }
 
-// Json prepares the r.report and r.totals data so that it can be written to a json file
+// TagTable prepares the r.tagTotals data so that it can be consumed by out.Table.
+// Each row contains a tag and the total time spent on it, sorted by tag. Records
+// with multiple tags are counted for each of their tags.
+func (r Reporter) TagTable() [][]string {
+	tags := make([]string, 0, len(r.tagTotals))
+	for tag := range r.tagTotals {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	rows := make([][]string, 0, len(tags))
+	for _, tag := range tags {
+		rows = append(rows, []string{tag, r.t.Formatter().FormatDuration(r.tagTotals[tag])})
+	}
+
+	return rows
+}
+
+// Json prepares the r.report and r.totals data so that it can be written to a json file.
+// The records, total time and billable amounts of each project are stored under the
+// project key in the "projects" object. The total time and billable amounts of all
+// projects and the total time per tag are stored in the "total" object.
 func (r Reporter) Json() ([]byte, error) {
-	var result = make(map[string]interface{})
+	var projects = make(map[string]interface{})
+	var totalSum time.Duration
 
 	for key, records := range r.report {
 		var total time.Duration
 		if t, ok := r.totals[key]; ok {
 			total = t
 		}
-		result[key] = map[string]interface{}{
+		amounts := r.amounts[key]
+		if amounts == nil {
+			amounts = make(map[string]float64)
+		}
+		projects[key] = map[string]interface{}{
 			"records": records,
 			"total":   total,
+			"amounts": amounts,
 		}
+		totalSum += total
+	}
+
+	result := map[string]interface{}{
+		"projects": projects,
+		"total": map[string]interface{}{
+			"total":   totalSum,
+			"amounts": r.TotalAmount(),
+			"tags":    r.tagTotals,
+		},
 	}
 	b, err := json.MarshalIndent(result, "", "\t")
 	if err != nil {
@@ -157,3 +310,66 @@ func (r Reporter) Json() ([]byte, error) {
 	}
 	return b, nil
 }
+
+// Csv prepares the r.report data as a flat list of records that can be written
+// to a CSV file. Each row represents one record, rows are sorted by start time.
+// The delimiter separates the fields of each row.
+func (r Reporter) Csv(delimiter rune) ([]byte, error) {
+	var records = make([]*Record, 0)
+	for _, projectRecords := range r.report {
+		records = append(records, projectRecords...)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Start.Before(records[j].Start)
+	})
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = delimiter
+
+	header := []string{"Date", "Project", "Module", "Start", "End", "Duration", "Billable", "Tags", "Note"}
+	if err := w.Write(header); err != nil {
+		return nil, fmt.Errorf("could not write report to csv: %s", err)
+	}
+
+	for _, record := range records {
+		keyParts := strings.Split(record.Project.Key, "@")
+		module, key := "", keyParts[0]
+		if len(keyParts) > 1 {
+			module = keyParts[0]
+			key = keyParts[1]
+		}
+		billable := "no"
+		if record.IsBillable {
+			billable = "yes"
+		}
+		end := ""
+		if record.End != nil {
+			end = r.t.Formatter().TimeString(*record.End)
+		}
+
+		row := []string{
+			r.t.Formatter().DateString(record.Start),
+			key,
+			module,
+			r.t.Formatter().TimeString(record.Start),
+			end,
+			r.t.Formatter().FormatCsvDuration(record.Duration()),
+			billable,
+			r.t.Formatter().FormatTags(record.Tags),
+			record.Note,
+		}
+
+		if err := w.Write(row); err != nil {
+			return nil, fmt.Errorf("could not write report to csv: %s", err)
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("could not write report to csv: %s", err)
+	}
+
+	return buf.Bytes(), nil
+}
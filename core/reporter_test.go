@@ -157,3 +157,177 @@ func TestProjectFilter(t *testing.T) {
 		}
 	}
 }
+
+func TestReporterCsv(t *testing.T) {
+	start1 := time.Date(2021, 06, 07, 8, 00, 00, 00, time.Local)
+	end1 := time.Date(2021, 06, 07, 9, 30, 00, 00, time.Local)
+	start2 := time.Date(2021, 06, 07, 10, 00, 00, 00, time.Local)
+	end2 := time.Date(2021, 06, 07, 10, 15, 00, 00, time.Local)
+
+	reporter := Reporter{
+		t: &Timetrace{
+			formatter: &Formatter{useDecimalHours: "On"},
+		},
+		report: map[string][]*Record{
+			"make-coffee": {
+				{Start: start2, End: &end2, Project: &Project{Key: "grind-beans@make-coffee"}, Tags: []string{"espresso"}},
+			},
+			"clean-kitchen": {
+				{Start: start1, End: &end1, Project: &Project{Key: "clean-kitchen"}, IsBillable: true, Note: "Mop the floor; twice"},
+			},
+		},
+		totals: make(map[string]time.Duration),
+	}
+
+	expected := "Date;Project;Module;Start;End;Duration;Billable;Tags;Note\n" +
+		"2021-06-07;clean-kitchen;;08:00;09:30;1.50;yes;;\"Mop the floor; twice\"\n" +
+		"2021-06-07;make-coffee;grind-beans;10:00;10:15;0.25;no;espresso;\n"
+
+	data, err := reporter.Csv(';')
+	if err != nil {
+		t.Fatalf("csv report: unexpected error: %s", err)
+	}
+
+	if string(data) != expected {
+		t.Fatalf("csv report: want:\n%s\nhave:\n%s", expected, string(data))
+	}
+}
+
+func TestReporterAmounts(t *testing.T) {
+	start := time.Date(2021, 06, 07, 8, 00, 00, 00, time.Local)
+	end := time.Date(2021, 06, 07, 9, 30, 00, 00, time.Local)
+
+	reporter := Reporter{
+		t:         &Timetrace{formatter: &Formatter{}},
+		report:    make(map[string][]*Record),
+		totals:    make(map[string]time.Duration),
+		amounts:   make(map[string]map[string]float64),
+		tagTotals: make(map[string]time.Duration),
+		rates: map[string]hourlyRate{
+			"make-coffee":             {rate: 80, currency: "EUR"},
+			"grind-beans@make-coffee": {rate: 100, currency: "EUR"},
+			"clean-kitchen":           {rate: 20, currency: "USD"},
+		},
+	}
+
+	reporter.sortAndMerge([]*Record{
+		{Start: start, End: &end, Project: &Project{Key: "make-coffee"}, IsBillable: true},
+		{Start: start, End: &end, Project: &Project{Key: "grind-beans@make-coffee"}, IsBillable: true},
+		{Start: start, End: &end, Project: &Project{Key: "make-coffee"}, IsBillable: false},
+		{Start: start, End: &end, Project: &Project{Key: "clean-kitchen"}, IsBillable: true},
+	})
+
+	if amount := reporter.amounts["make-coffee"]["EUR"]; amount != 270 {
+		t.Errorf("amount for make-coffee: want 270, have %v", amount)
+	}
+
+	if total := reporter.t.Formatter().FormatAmounts(reporter.TotalAmount()); total != "270.00 EUR, 30.00 USD" {
+		t.Errorf("total amount: want 270.00 EUR, 30.00 USD, have %s", total)
+	}
+}
+
+func TestReporterJson(t *testing.T) {
+	start := time.Date(2021, 06, 07, 8, 00, 00, 00, time.Local)
+	end := time.Date(2021, 06, 07, 9, 30, 00, 00, time.Local)
+
+	reporter := Reporter{
+		t:         &Timetrace{formatter: &Formatter{}},
+		report:    make(map[string][]*Record),
+		totals:    make(map[string]time.Duration),
+		amounts:   make(map[string]map[string]float64),
+		tagTotals: make(map[string]time.Duration),
+		rates: map[string]hourlyRate{
+			"make-coffee": {rate: 80, currency: "EUR"},
+		},
+	}
+
+	reporter.sortAndMerge([]*Record{
+		{Start: start, End: &end, Project: &Project{Key: "make-coffee"}, IsBillable: true, Tags: []string{"espresso"}},
+	})
+
+	data, err := reporter.Json()
+	if err != nil {
+		t.Fatalf("json report: unexpected error: %s", err)
+	}
+
+	var report struct {
+		Projects map[string]struct {
+			Records []Record           `json:"records"`
+			Total   time.Duration      `json:"total"`
+			Amounts map[string]float64 `json:"amounts"`
+		} `json:"projects"`
+		Total struct {
+			Total   time.Duration            `json:"total"`
+			Amounts map[string]float64       `json:"amounts"`
+			Tags    map[string]time.Duration `json:"tags"`
+		} `json:"total"`
+	}
+
+	if err := decodeStrict(data, &report); err != nil {
+		t.Fatalf("json report: unexpected shape: %s\n%s", err, data)
+	}
+
+	project, ok := report.Projects["make-coffee"]
+	if !ok || len(report.Projects) != 1 {
+		t.Fatalf("json report: expected only make-coffee in projects, have %v", report.Projects)
+	}
+	if len(project.Records) != 1 || project.Total != 90*time.Minute || project.Amounts["EUR"] != 120 {
+		t.Errorf("json report: unexpected project %+v", project)
+	}
+	if report.Total.Total != 90*time.Minute || report.Total.Amounts["EUR"] != 120 || report.Total.Tags["espresso"] != 90*time.Minute {
+		t.Errorf("json report: unexpected total %+v", report.Total)
+	}
+}
+
+func TestTagFilter(t *testing.T) {
+	tests := map[string]struct {
+		tags     []string
+		all      bool
+		record   Record
+		expected bool
+	}{
+		"any matches": {
+			tags:     []string{"espresso", "latte"},
+			record:   Record{Tags: []string{"latte"}},
+			expected: true,
+		},
+		"any doesn't match": {
+			tags:     []string{"espresso", "latte"},
+			record:   Record{Tags: []string{"tea"}},
+			expected: false,
+		},
+		"all matches": {
+			tags:     []string{"espresso", "morning"},
+			all:      true,
+			record:   Record{Tags: []string{"morning", "espresso", "dark"}},
+			expected: true,
+		},
+		"all doesn't match": {
+			tags:     []string{"espresso", "morning"},
+			all:      true,
+			record:   Record{Tags: []string{"espresso"}},
+			expected: false,
+		},
+		"negation only": {
+			tags:     []string{"!meeting"},
+			record:   Record{Tags: []string{"espresso"}},
+			expected: true,
+		},
+		"negation excludes": {
+			tags:     []string{"espresso", "!meeting"},
+			record:   Record{Tags: []string{"espresso", "meeting"}},
+			expected: false,
+		},
+		"untagged record": {
+			tags:     []string{"espresso"},
+			record:   Record{},
+			expected: false,
+		},
+	}
+
+	for name, tc := range tests {
+		if filtered := FilterByTag(tc.tags, tc.all)(&tc.record); filtered != tc.expected {
+			t.Errorf("%s: want %v, have %v", name, tc.expected, filtered)
+		}
+	}
+}
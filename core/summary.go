@@ -0,0 +1,358 @@
+package core
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Supported groupings for summary reports.
+const (
+	SummaryGroupByDay     = "day"
+	SummaryGroupByWeek    = "week"
+	SummaryGroupByMonth   = "month"
+	SummaryGroupByProject = "project"
+	SummaryGroupByTag     = "tag"
+)
+
+var (
+	ErrUnknownSummaryGrouping = errors.New("unknown summary grouping")
+)
+
+// Summary is a pivot table of the tracked time. For the day and month
+// groupings, each row represents a project and each column represents a time
+// period. For the week grouping, each row represents a project within a week
+// and each column represents a weekday, just like a timesheet. For the project
+// and tag groupings, each row represents a project or tag and there are no
+// columns besides the row totals.
+type Summary struct {
+	t       *Timetrace
+	GroupBy string `json:"group_by"`
+	// Columns contains the labels of the time periods or weekdays.
+	Columns []string     `json:"columns"`
+	Rows    []SummaryRow `json:"rows"`
+	// ColumnTotals contains the tracked time of each time period or weekday.
+	ColumnTotals []time.Duration `json:"column_totals"`
+	// Total is the overall tracked time. When grouping by tag, it may be less
+	// than the sum of all rows since records with multiple tags are counted
+	// for each of their tags.
+	Total time.Duration `json:"total"`
+}
+
+// SummaryRow contains the tracked time of a project or tag for each column of
+// a Summary. Period is only set when grouping by week and contains the week.
+type SummaryRow struct {
+	Period    string          `json:"period,omitempty"`
+	Key       string          `json:"key"`
+	Durations []time.Duration `json:"durations"`
+	Total     time.Duration   `json:"total"`
+}
+
+// Summary creates a pivot table of all records in the report, grouped by the
+// given grouping. For the day and month groupings, all time periods between
+// from and to are included, even if no time has been tracked within a period.
+// If from or to is zero, the start of the oldest or youngest record is used
+// instead. When grouping by week, there is one row per project and week with
+// one column per weekday. Weeks start on Monday and are labeled with their ISO
+// week number.
+func (r Reporter) Summary(groupBy string, from, to time.Time) (*Summary, error) {
+	var records []*Record
+	for _, projectRecords := range r.report {
+		records = append(records, projectRecords...)
+	}
+
+	summary := &Summary{
+		t:            r.t,
+		GroupBy:      groupBy,
+		Columns:      make([]string, 0),
+		Rows:         make([]SummaryRow, 0),
+		ColumnTotals: make([]time.Duration, 0),
+	}
+
+	// rowKeys returns the keys of the rows a record is counted for.
+	var rowKeys func(*Record) []string
+	// column returns the index of the column a record is counted for.
+	var column func(*Record) int
+	// period returns the period of the row a record is counted for.
+	period := func(*Record) string { return "" }
+
+	parentKey := func(record *Record) []string {
+		if record.Project.IsModule() {
+			return []string{record.Project.Parent()}
+		}
+		return []string{record.Project.Key}
+	}
+
+	switch groupBy {
+	case SummaryGroupByWeek:
+		rowKeys = parentKey
+		for day := 0; day < 7; day++ {
+			summary.Columns = append(summary.Columns, time.Weekday((day + 1) % 7).String()[:3])
+		}
+		column = func(record *Record) int {
+			// time.Weekday starts on Sunday, ISO weeks start on Monday.
+			return (int(record.Start.Weekday()) + 6) % 7
+		}
+		period = func(record *Record) string {
+			return summaryPeriodLabel(summaryPeriodStart(record.Start, groupBy), groupBy)
+		}
+	case SummaryGroupByDay, SummaryGroupByMonth:
+		rowKeys = parentKey
+		periods := summaryPeriods(records, groupBy, from, to)
+		for _, period := range periods {
+			summary.Columns = append(summary.Columns, summaryPeriodLabel(period, groupBy))
+		}
+		column = func(record *Record) int {
+			start := summaryPeriodStart(record.Start, groupBy)
+			return sort.Search(len(periods), func(i int) bool {
+				return !periods[i].Before(start)
+			})
+		}
+	case SummaryGroupByProject:
+		rowKeys = func(record *Record) []string {
+			return []string{record.Project.Key}
+		}
+	case SummaryGroupByTag:
+		rowKeys = func(record *Record) []string {
+			if len(record.Tags) == 0 {
+				return []string{untaggedItem}
+			}
+			return uniqueTags(record.Tags)
+		}
+	default:
+		return nil, ErrUnknownSummaryGrouping
+	}
+
+	summary.ColumnTotals = make([]time.Duration, len(summary.Columns))
+	rows := make(map[string]*SummaryRow)
+
+	for _, record := range records {
+		duration := record.Duration()
+		summary.Total += duration
+
+		recordPeriod := period(record)
+
+		for _, key := range rowKeys(record) {
+			row, ok := rows[recordPeriod+"/"+key]
+			if !ok {
+				row = &SummaryRow{
+					Period:    recordPeriod,
+					Key:       key,
+					Durations: make([]time.Duration, len(summary.Columns)),
+				}
+				rows[recordPeriod+"/"+key] = row
+			}
+
+			row.Total += duration
+
+			if column != nil {
+				row.Durations[column(record)] += duration
+			}
+		}
+
+		if column != nil {
+			summary.ColumnTotals[column(record)] += duration
+		}
+	}
+
+	for _, row := range rows {
+		summary.Rows = append(summary.Rows, *row)
+	}
+
+	sort.Slice(summary.Rows, func(i, j int) bool {
+		if summary.Rows[i].Period != summary.Rows[j].Period {
+			return summary.Rows[i].Period < summary.Rows[j].Period
+		}
+		return summary.Rows[i].Key < summary.Rows[j].Key
+	})
+
+	return summary, nil
+}
+
+// Table prepares the summary so that it can be consumed by out.Table. It
+// returns the header, one row per project or tag and the footer with the totals.
+// When grouping by week, each row starts with the week.
+func (s *Summary) Table() ([]string, [][]string, []string) {
+	label := "Project"
+	if s.GroupBy == SummaryGroupByTag {
+		label = "Tag"
+	}
+
+	header := append(append(s.leadingCells("Week", label), s.Columns...), "Total")
+	rows := make([][]string, 0, len(s.Rows))
+
+	for _, row := range s.Rows {
+		cells := s.leadingCells(row.Period, row.Key)
+		for _, duration := range row.Durations {
+			cells = append(cells, s.formatCell(duration))
+		}
+		rows = append(rows, append(cells, s.t.Formatter().FormatDuration(row.Total)))
+	}
+
+	footer := s.footerCells("TOTAL")
+	for _, duration := range s.ColumnTotals {
+		footer = append(footer, s.t.Formatter().FormatDuration(duration))
+	}
+	footer = append(footer, s.t.Formatter().FormatDuration(s.Total))
+
+	return header, rows, footer
+}
+
+// Json returns the summary as JSON. All durations are stored in nanoseconds,
+// just like the totals of a JSON report.
+func (s *Summary) Json() ([]byte, error) {
+	b, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal summary to json")
+	}
+	return b, nil
+}
+
+// Csv returns the summary as CSV with the same rows and columns as Table. The
+// delimiter separates the fields of each row.
+func (s *Summary) Csv(delimiter rune) ([]byte, error) {
+	header, _, _ := s.Table()
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = delimiter
+
+	lines := [][]string{header}
+
+	for _, row := range s.Rows {
+		line := s.leadingCells(row.Period, row.Key)
+		for _, duration := range row.Durations {
+			line = append(line, s.t.Formatter().FormatCsvDuration(duration))
+		}
+		lines = append(lines, append(line, s.t.Formatter().FormatCsvDuration(row.Total)))
+	}
+
+	footer := s.footerCells("Total")
+	for _, duration := range s.ColumnTotals {
+		footer = append(footer, s.t.Formatter().FormatCsvDuration(duration))
+	}
+	lines = append(lines, append(footer, s.t.Formatter().FormatCsvDuration(s.Total)))
+
+	if err := w.WriteAll(lines); err != nil {
+		return nil, fmt.Errorf("could not write summary to csv: %s", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// leadingCells returns the cells in front of the durations of a row. The
+// period cell only exists when grouping by week.
+func (s *Summary) leadingCells(period, key string) []string {
+	if s.GroupBy == SummaryGroupByWeek {
+		return []string{period, key}
+	}
+	return []string{key}
+}
+
+// footerCells returns the cells in front of the column totals.
+func (s *Summary) footerCells(label string) []string {
+	if s.GroupBy == SummaryGroupByWeek {
+		return []string{label, ""}
+	}
+	return []string{label}
+}
+
+// formatCell formats the duration of a table cell, leaving cells without
+// tracked time empty so that the table stays readable.
+func (s *Summary) formatCell(duration time.Duration) string {
+	if duration == 0 {
+		return ""
+	}
+	return s.t.Formatter().FormatDuration(duration)
+}
+
+// summaryPeriods returns the start of all time periods between first and last.
+// A zero boundary is replaced with the start of the oldest or youngest record.
+func summaryPeriods(records []*Record, groupBy string, first, last time.Time) []time.Time {
+	periods := make([]time.Time, 0)
+
+	for _, record := range records {
+		// A record is always contained in the periods, even if it started
+		// before the given boundaries.
+		if first.IsZero() || record.Start.Before(first) {
+			first = record.Start
+		}
+		if last.IsZero() || record.Start.After(last) {
+			last = record.Start
+		}
+	}
+
+	if first.IsZero() || last.IsZero() {
+		return periods
+	}
+
+	last = summaryPeriodStart(last, groupBy)
+
+	for period := summaryPeriodStart(first, groupBy); !period.After(last); period = nextSummaryPeriod(period, groupBy) {
+		periods = append(periods, period)
+	}
+
+	return periods
+}
+
+// summaryPeriodStart returns the start of the time period containing the given
+// time in the time's own location, which is the home time zone for parsed
+// dates and loaded records.
+func summaryPeriodStart(t time.Time, groupBy string) time.Time {
+	year, month, day := t.Date()
+	loc := t.Location()
+
+	switch groupBy {
+	case SummaryGroupByWeek:
+		start := time.Date(year, month, day, 0, 0, 0, 0, loc)
+		// time.Weekday starts on Sunday, ISO weeks start on Monday.
+		offset := (int(start.Weekday()) + 6) % 7
+		return start.AddDate(0, 0, -offset)
+	case SummaryGroupByMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(year, month, day, 0, 0, 0, 0, loc)
+	}
+}
+
+func nextSummaryPeriod(period time.Time, groupBy string) time.Time {
+	switch groupBy {
+	case SummaryGroupByMonth:
+		return period.AddDate(0, 1, 0)
+	default:
+		return period.AddDate(0, 0, 1)
+	}
+}
+
+func summaryPeriodLabel(period time.Time, groupBy string) string {
+	switch groupBy {
+	case SummaryGroupByWeek:
+		year, week := period.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case SummaryGroupByMonth:
+		return period.Format("2006-01")
+	default:
+		return period.Format("Mon " + dateLayout)
+	}
+}
+
+// uniqueTags returns the given tags without duplicates, so that a record isn't
+// counted twice for the same tag.
+func uniqueTags(tags []string) []string {
+	seen := make(map[string]bool)
+	unique := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		unique = append(unique, tag)
+	}
+
+	return unique
+}
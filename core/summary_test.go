@@ -0,0 +1,88 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReporterSummary(t *testing.T) {
+	// 2021-06-07 is a Monday.
+	at := func(day, hour int) *time.Time {
+		t := time.Date(2021, 06, day, hour, 00, 00, 00, time.Local)
+		return &t
+	}
+
+	reporter := Reporter{
+		t: &Timetrace{formatter: &Formatter{}},
+		report: map[string][]*Record{
+			"make-coffee": {
+				{Start: *at(7, 8), End: at(7, 10), Project: &Project{Key: "make-coffee"}, Tags: []string{"espresso"}},
+				{Start: *at(9, 8), End: at(9, 9), Project: &Project{Key: "grind-beans@make-coffee"}, Tags: []string{"espresso", "beans"}},
+			},
+			"clean-kitchen": {
+				{Start: *at(14, 8), End: at(14, 11), Project: &Project{Key: "clean-kitchen"}},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		groupBy string
+		from    time.Time
+		to      time.Time
+		columns []string
+		rows    []SummaryRow
+		total   time.Duration
+	}{
+		"week": {
+			groupBy: SummaryGroupByWeek,
+			columns: []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"},
+			rows: []SummaryRow{
+				{Period: "2021-W23", Key: "make-coffee", Durations: []time.Duration{2 * time.Hour, 0, time.Hour, 0, 0, 0, 0}, Total: 3 * time.Hour},
+				{Period: "2021-W24", Key: "clean-kitchen", Durations: []time.Duration{3 * time.Hour, 0, 0, 0, 0, 0, 0}, Total: 3 * time.Hour},
+			},
+			total: 6 * time.Hour,
+		},
+		"day with boundaries": {
+			groupBy: SummaryGroupByDay,
+			from:    *at(6, 0),
+			to:      *at(9, 0),
+			columns: []string{"Sun 2021-06-06", "Mon 2021-06-07", "Tue 2021-06-08", "Wed 2021-06-09", "Thu 2021-06-10",
+				"Fri 2021-06-11", "Sat 2021-06-12", "Sun 2021-06-13", "Mon 2021-06-14"},
+			rows: []SummaryRow{
+				{Key: "clean-kitchen", Durations: []time.Duration{0, 0, 0, 0, 0, 0, 0, 0, 3 * time.Hour}, Total: 3 * time.Hour},
+				{Key: "make-coffee", Durations: []time.Duration{0, 2 * time.Hour, 0, time.Hour, 0, 0, 0, 0, 0}, Total: 3 * time.Hour},
+			},
+			total: 6 * time.Hour,
+		},
+		"tag": {
+			groupBy: SummaryGroupByTag,
+			columns: []string{},
+			rows: []SummaryRow{
+				{Key: "beans", Durations: []time.Duration{}, Total: time.Hour},
+				{Key: "espresso", Durations: []time.Duration{}, Total: 3 * time.Hour},
+				{Key: "untagged", Durations: []time.Duration{}, Total: 3 * time.Hour},
+			},
+			total: 6 * time.Hour,
+		},
+	}
+
+	for name, tc := range tests {
+		summary, err := reporter.Summary(tc.groupBy, tc.from, tc.to)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+
+		if !reflect.DeepEqual(summary.Columns, tc.columns) {
+			t.Errorf("%s: expected columns %v, got %v", name, tc.columns, summary.Columns)
+		}
+
+		if !reflect.DeepEqual(summary.Rows, tc.rows) {
+			t.Errorf("%s: expected rows %v, got %v", name, tc.rows, summary.Rows)
+		}
+
+		if summary.Total != tc.total {
+			t.Errorf("%s: expected total %v, got %v", name, tc.total, summary.Total)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var (
+	ErrTagNotFound      = errors.New("tag not found")
+	ErrTagAlreadyExists = errors.New("tag already exists")
+	ErrInvalidTag       = errors.New("tags must not be empty, contain commas or whitespace or start with " + tagNegationPrefix)
+	ErrUnknownTag       = errors.New("unknown tag, create it first or disable strict tags")
+)
+
+// Tag is a registered tag. Records can be tagged with any tag, but if strict
+// tags are enabled in the config, only registered tags are allowed.
+type Tag struct {
+	Key         string `json:"key"`
+	Description string `json:"description,omitempty"`
+	Color       string `json:"color,omitempty"`
+}
+
+// LoadTag loads the registered tag with the given key. Returns ErrTagNotFound
+// if the tag cannot be found.
+func (t *Timetrace) LoadTag(key string) (*Tag, error) {
+	data, err := t.fs.LoadTag(key)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrTagNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var tag Tag
+
+	if err := json.Unmarshal(data, &tag); err != nil {
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+// ListTags loads and returns all registered tags sorted by their key.
+func (t *Timetrace) ListTags() ([]*Tag, error) {
+	keys, err := t.fs.TagKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]*Tag, 0)
+
+	for _, key := range keys {
+		tag, err := t.LoadTag(key)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+// SaveTag registers the given tag. Returns ErrTagAlreadyExists if the tag
+// already exists and saving isn't forced.
+func (t *Timetrace) SaveTag(tag Tag, force bool) error {
+	if !isValidTag(tag.Key) {
+		return fmt.Errorf("%w: %s", ErrInvalidTag, tag.Key)
+	}
+
+	if _, err := t.fs.LoadTag(tag.Key); err == nil && !force {
+		return ErrTagAlreadyExists
+	}
+
+	bytes, err := json.MarshalIndent(&tag, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return t.fs.SaveTag(tag.Key, bytes)
+}
+
+// DeleteTag removes the tag with the given key from the registry. Records
+// tagged with it keep the tag. Returns ErrTagNotFound if the tag doesn't exist.
+func (t *Timetrace) DeleteTag(key string) error {
+	err := t.fs.DeleteTag(key)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrTagNotFound
+	}
+
+	return err
+}
+
+// ValidateTags checks if the given tags may be used for a record. Tags must be
+// valid, and if strict tags are enabled in the config, they must be registered.
+func (t *Timetrace) ValidateTags(tags []string) error {
+	for _, tag := range tags {
+		if !isValidTag(tag) {
+			return fmt.Errorf("%w: %s", ErrInvalidTag, tag)
+		}
+
+		if !t.config.StrictTags {
+			continue
+		}
+
+		if _, err := t.LoadTag(tag); errors.Is(err, ErrTagNotFound) {
+			return fmt.Errorf("%w: %s", ErrUnknownTag, tag)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isValidTag checks if the tag can be stored and filtered for. Commas would
+// split the tag when passed to --tag and the negation prefix would negate it.
+func isValidTag(tag string) bool {
+	if tag == "" || strings.HasPrefix(tag, tagNegationPrefix) || strings.Contains(tag, ",") {
+		return false
+	}
+
+	return len(strings.Fields(tag)) == 1 && strings.TrimSpace(tag) == tag
+}
@@ -0,0 +1,21 @@
+package core
+
+import "testing"
+
+func TestIsValidTag(t *testing.T) {
+	tests := map[string]bool{
+		"review":      true,
+		"code-review": true,
+		"":            false,
+		"!review":     false,
+		"code,review": false,
+		"code review": false,
+		" review":     false,
+	}
+
+	for tag, expected := range tests {
+		if valid := isValidTag(tag); valid != expected {
+			t.Errorf("%q: expected valid to be %v, got %v", tag, expected, valid)
+		}
+	}
+}
@@ -2,9 +2,9 @@ package core
 
 import (
 	"errors"
-	"io"
+	"fmt"
 	"os"
-	"path/filepath"
+	"sort"
 	"strconv"
 	"time"
 
@@ -12,69 +12,123 @@ import (
 	"github.com/dominikbraun/timetrace/out"
 )
 
-const (
-	BakFileExt = ".bak"
-)
-
 var (
-	ErrNoEndTime           = errors.New("no end time for last record")
-	ErrTrackingNotStarted  = errors.New("start tracking first")
-	ErrAllDirectoriesEmpty = errors.New("all directories empty")
+	ErrNoEndTime          = errors.New("no end time for last record")
+	ErrTrackingNotStarted = errors.New("start tracking first")
+	ErrAlreadyPaused      = errors.New("tracking is already paused")
+	ErrNotPaused          = errors.New("tracking is not paused")
+	ErrRecordNotRunning   = errors.New("record is not running")
+	ErrEndBeforeStart     = errors.New("end time is before the start of the record or its last pause")
+	ErrFutureTime         = errors.New("time is in the future")
+	ErrOverlapsPrevious   = errors.New("start time is before the end of the previous record")
+	ErrParallelTooEarly   = errors.New("parallel records can't start before the latest non-parallel record")
+	ErrSwitchTooSoon      = errors.New("the running record has started less than a minute ago, edit it instead")
+	ErrContinueTooSoon    = errors.New("a record has already started within the current minute, please continue in a minute")
+
+	ErrMultipleRecordsRunning = errors.New("multiple records are running, please specify a project or record")
+	ErrStoreLocked            = errors.New("another timetrace process is modifying the store, please try again")
 )
 
+// defaultLockTimeout is the time to wait for other processes to release the
+// store lock if no timeout is configured.
+const defaultLockTimeout = 5 * time.Second
+
 type Report struct {
 	Current            *Record
+	Running            []*Record
 	TrackedTimeCurrent *time.Duration
 	TrackedTimeToday   time.Duration
 	BreakTimeToday     time.Duration
 }
 
-// Filesystem represents a filesystem used for storing and loading resources.
+// Filesystem represents a storage backend used for storing and loading
+// resources. Projects and tags are identified by their key, records are
+// identified by their start time with minute precision. Resources are passed as serialized
+// JSON documents, so storage backends don't need to know their structure.
+//
+// Load and Delete methods return an error wrapping os.ErrNotExist if the
+// requested resource doesn't exist. Lock returns an error wrapping
+// os.ErrDeadlineExceeded if the store is locked by another process.
 type Filesystem interface {
-	ProjectFilepath(key string) string
-	ProjectBackupFilepath(key string) string
-	ProjectFilepaths() ([]string, error)
-	ProjectBackupFilepaths() ([]string, error)
-	RecordFilepath(start time.Time) string
-	RecordBackupFilepath(start time.Time) string
-	RecordFilepaths(dir string, less func(a, b string) bool) ([]string, error)
-	RecordDirs() ([]string, error)
-	ReportDir() string
-	RecordDirFromDate(date time.Time) string
+	ProjectKeys() ([]string, error)
+	ProjectBackupKeys() ([]string, error)
+	LoadProject(key string) ([]byte, error)
+	LoadProjectBackup(key string) ([]byte, error)
+	SaveProject(key string, data []byte) error
+	SaveProjectBackup(key string, data []byte) error
+	DeleteProject(key string) error
+	RecordKeys(from, to time.Time) ([]time.Time, error)
+	RecordBackupKeys(from, to time.Time) ([]time.Time, error)
+	LatestRecordKey() (time.Time, error)
+	LoadRecord(start time.Time) ([]byte, error)
+	LoadRecordBackup(start time.Time) ([]byte, error)
+	SaveRecord(start time.Time, data []byte) error
+	SaveRecordBackup(start time.Time, data []byte) error
+	DeleteRecord(start time.Time) error
+	TagKeys() ([]string, error)
+	LoadTag(key string) ([]byte, error)
+	SaveTag(key string, data []byte) error
+	DeleteTag(key string) error
 	EnsureDirectories() error
-	EnsureRecordDir(date time.Time) error
 	WriteReport(path string, data []byte) error
+	Lock(timeout time.Duration) error
+	Unlock() error
+	JournalEntries() ([][]byte, error)
+	LatestJournalEntry() ([]byte, error)
+	AppendJournalEntry(data []byte) error
 }
 
 type Timetrace struct {
 	config    *config.Config
 	fs        Filesystem
 	formatter *Formatter
+	// locked is set while the store lock acquired by Lock is held.
+	locked bool
 }
 
+// New creates a Timetrace instance. An invalid time zone in the config falls
+// back to the local time zone, so timetrace keeps working; callers may check
+// the time zone using config.Config.Location beforehand.
 func New(config *config.Config, fs Filesystem) *Timetrace {
+	location, err := config.Location()
+	if err != nil {
+		location = time.Local
+	}
+
 	return &Timetrace{
 		config: config,
-		fs:     fs,
+		fs:     &journalingFs{Filesystem: fs, location: location},
 		formatter: &Formatter{
 			useDecimalHours: config.UseDecimalHours,
 			use12Hours:      config.Use12Hours,
+			location:        location,
 		},
 	}
 }
 
 // Start starts tracking time for the given project key. This will create a new
-// record with the current time as start time.
+// record with the current time as start time. If at isn't zero, the record is
+// back-dated to start at the given time instead, which is checked using
+// checkStartTime.
+//
+// Parallel work is only supported for parallel records: If isParallel is set,
+// the new record may run alongside other records. Otherwise, all running
+// records must be stopped first.
 //
-// Since parallel work isn't supported, the previous work must be stopped first.
-func (t *Timetrace) Start(projectKey string, isBillable bool, tags []string) error {
-	latestRecord, err := t.LoadLatestRecord()
-	if err != nil && !errors.Is(err, ErrAllDirectoriesEmpty) {
+// The note is an optional description of the work done within the record.
+// The tags are checked using ValidateTags.
+func (t *Timetrace) Start(projectKey string, isBillable bool, tags []string, isParallel bool, note string, at time.Time) error {
+	if err := t.ValidateTags(tags); err != nil {
 		return err
 	}
 
-	// If there is no end time of the latest record, the user has to stop first.
-	if latestRecord != nil && latestRecord.End == nil {
+	runningRecords, err := t.LoadRunningRecords()
+	if err != nil {
+		return err
+	}
+
+	// If there is a record without end time, the user has to stop first.
+	if len(runningRecords) > 0 && !isParallel {
 		return ErrNoEndTime
 	}
 
@@ -87,33 +141,127 @@ func (t *Timetrace) Start(projectKey string, isBillable bool, tags []string) err
 	}
 
 	record := Record{
-		Start:      time.Now(),
+		Start:      t.formatter.Now(),
 		Project:    project,
 		IsBillable: isBillable,
 		Tags:       tags,
+		IsParallel: isParallel,
+		Note:       note,
+	}
+
+	if !at.IsZero() {
+		record.Start = at
+		if err := t.checkStartTime(record); err != nil {
+			return err
+		}
 	}
 
 	return t.SaveRecord(record, false)
 }
 
+// Continue starts a new record with the same project, tags, billable flag and
+// note as the given record, e.g. to resume work after a break. Just like for
+// Start, running records have to be stopped first unless the given record is
+// a parallel record.
+//
+// Since records are identified by their start minute, continuing within the
+// minute another record has started in returns ErrContinueTooSoon.
+func (t *Timetrace) Continue(record Record) error {
+	if !record.IsParallel {
+		runningRecords, err := t.LoadRunningRecords()
+		if err != nil {
+			return err
+		}
+
+		if len(runningRecords) > 0 {
+			running := runningRecords[len(runningRecords)-1]
+			return fmt.Errorf("%w: %s is still running and has to be stopped first",
+				ErrNoEndTime, t.formatter.RecordKey(running))
+		}
+	}
+
+	if _, err := t.fs.LoadRecord(t.formatter.Now()); err == nil {
+		return ErrContinueTooSoon
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	var projectKey string
+	if record.Project != nil {
+		projectKey = record.Project.Key
+	}
+
+	return t.Start(projectKey, record.IsBillable, record.Tags, record.IsParallel, record.Note, time.Time{})
+}
+
+// checkStartTime checks if a back-dated record may start at its start time,
+// just like records created for the past: The start time must not be in the
+// future or before the end of the previous record, and the record must not
+// collide with any later record. Parallel records may overlap other records.
+func (t *Timetrace) checkStartTime(record Record) error {
+	if record.Start.After(t.formatter.Now()) {
+		return ErrFutureTime
+	}
+
+	if !record.IsParallel {
+		previous, err := t.previousRecord(record.Start)
+		if err != nil {
+			return err
+		}
+
+		if previous != nil && previous.End != nil && previous.End.After(record.Start) {
+			return fmt.Errorf("%w: %s ended at %s", ErrOverlapsPrevious,
+				t.formatter.RecordKey(previous), t.formatter.TimeString(*previous.End))
+		}
+	}
+
+	return t.assertNoCollisions(record, time.Time{})
+}
+
+// previousRecord returns the latest non-parallel record that started before
+// the given time, or nil if there is none. If the time is zero, the latest
+// non-parallel record is returned.
+func (t *Timetrace) previousRecord(before time.Time) (*Record, error) {
+	keys, err := t.fs.RecordKeys(time.Time{}, before)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(keys) - 1; i >= 0; i-- {
+		record, err := t.LoadRecord(keys[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if !record.IsParallel {
+			return record, nil
+		}
+	}
+
+	return nil, nil
+}
+
 // Status calculates and returns a status report.
 //
 // If the user isn't tracking time at the moment of calling this function, the
 // Report.Current and Report.TrackedTimeCurrent fields will be nil. If the user
 // hasn't tracked time today, ErrTrackingNotStarted will be returned.
+//
+// If multiple parallel records are running, Report.Running contains all of
+// them and Report.Current is the most recently started one.
 func (t *Timetrace) Status() (*Report, error) {
-	now := time.Now()
+	now := t.formatter.Now()
 
-	firstRecord, err := t.loadOldestRecord(time.Now())
+	todaysRecords, err := t.ListRecords(now)
 	if err != nil {
 		return nil, err
 	}
 
-	if firstRecord == nil {
+	if len(todaysRecords) == 0 {
 		return nil, ErrTrackingNotStarted
 	}
 
-	latestRecord, err := t.LoadLatestRecord()
+	runningRecords, err := t.LoadRunningRecords()
 	if err != nil {
 		return nil, err
 	}
@@ -129,83 +277,405 @@ func (t *Timetrace) Status() (*Report, error) {
 	}
 
 	report := &Report{
+		Running:          runningRecords,
 		TrackedTimeToday: trackedTimeToday,
 		BreakTimeToday:   breakTimeToday,
 	}
 
-	// If the latest record has been stopped, there is no active time tracking.
+	// If all records have been stopped, there is no active time tracking.
 	// Therefore, just calculate the tracked time of today and return.
-	if latestRecord.End != nil {
+	if len(runningRecords) == 0 {
 		return report, nil
 	}
 
-	report.Current = latestRecord
+	report.Current = runningRecords[len(runningRecords)-1]
 
 	// If the latest record has not been stopped yet, time tracking is active.
 	// Calculate the time tracked for the current record and for today.
-	trackedTimeCurrent := latestRecord.Duration()
+	trackedTimeCurrent := report.Current.Duration()
 	report.TrackedTimeCurrent = &trackedTimeCurrent
 
 	return report, nil
 }
 
+// breakTime calculates the time between the start of the first record and the
+// end of the last record of the given date in which no record was active, i.e.
+// the time between records and the time records have been paused. Records
+// spanning midnight are only considered within the given date.
 func (t *Timetrace) breakTime(date time.Time) (time.Duration, error) {
-	records, err := t.loadAllRecordsSortedAscending(date)
+	records, err := t.ListRecords(date)
 	if err != nil {
 		return 0, err
 	}
 
-	// add up the time between records
-	var breakTime time.Duration
-	for i := 0; i < len(records)-1; i++ {
-		breakTime += records[i+1].Start.Sub(*records[i].End)
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	from, to := dayRange(date)
+
+	// Collect the intervals in which records were active. Since parallel
+	// records may overlap, merge them before adding up the worked time.
+	var intervals [][2]time.Time
+	var firstStart, lastEnd time.Time
+
+	for _, record := range records {
+		for _, interval := range record.activeIntervals() {
+			if interval[0].Before(from) {
+				interval[0] = from
+			}
+			if interval[1].After(to) {
+				interval[1] = to
+			}
+			intervals = append(intervals, interval)
+		}
+
+		if start := record.Start; firstStart.IsZero() || start.Before(firstStart) {
+			firstStart = start
+		}
+		if end := record.endOrNow(); end.After(lastEnd) {
+			lastEnd = end
+		}
+	}
+
+	if firstStart.Before(from) {
+		firstStart = from
+	}
+	if lastEnd.After(to) {
+		lastEnd = to
+	}
+
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i][0].Before(intervals[j][0])
+	})
+
+	var activeTime time.Duration
+	var coveredUntil time.Time
+
+	for _, interval := range intervals {
+		start, end := interval[0], interval[1]
+		if start.Before(coveredUntil) {
+			start = coveredUntil
+		}
+		if end.After(start) {
+			activeTime += end.Sub(start)
+			coveredUntil = end
+		}
 	}
 
-	return breakTime, nil
+	return lastEnd.Sub(firstStart) - activeTime, nil
 }
 
-// Stop stops the time tracking and marks the current record as ended.
-func (t *Timetrace) Stop() error {
-	latestRecord, err := t.LoadLatestRecord()
+// Stop stops the time tracking and marks the current record as ended. If
+// multiple parallel records are running, the project key of the record to be
+// stopped has to be provided. Otherwise, it may be empty.
+//
+// If at isn't zero, the record ends at the given time instead of now. It must
+// neither be in the future nor before the record or its last pause started.
+func (t *Timetrace) Stop(projectKey string, at time.Time) error {
+	record, err := t.runningRecord(projectKey)
 	if err != nil {
 		return err
 	}
 
-	if latestRecord == nil || latestRecord.End != nil {
-		return ErrTrackingNotStarted
+	return t.stopRecord(record, at)
+}
+
+// StopRecord works like Stop, but stops the running record with the given
+// start time. This allows stopping one of multiple parallel records of the
+// same project. Returns ErrRecordNotRunning if the record has already ended.
+func (t *Timetrace) StopRecord(recordKey time.Time, at time.Time) error {
+	record, err := t.LoadRecord(recordKey)
+	if err != nil {
+		return err
 	}
 
-	end := time.Now()
-	latestRecord.End = &end
+	if record.End != nil {
+		return ErrRecordNotRunning
+	}
 
-	return t.SaveRecord(*latestRecord, true)
+	return t.stopRecord(record, at)
 }
 
-// Report generates a report of tracked times
+// stopRecord ends the given running record at the given time or now if at is
+// zero, after checking the end time just like documented for Stop.
+func (t *Timetrace) stopRecord(record *Record, at time.Time) error {
+	end := t.formatter.Now()
+	if !at.IsZero() {
+		if at.After(end) {
+			return ErrFutureTime
+		}
+		if at.Before(record.lastActivity()) {
+			return ErrEndBeforeStart
+		}
+		end = at
+	}
+
+	return t.finishRecord(record, end)
+}
+
+// Switch stops the running record and starts tracking time for the given
+// project as a single operation, so that the running record ends exactly when
+// the new record starts. If isBillable is nil, the billable default configured
+// for the project is used. The tags are checked using ValidateTags.
 //
-// The report can be filtered by the given Filter* funcs. By default
-// all records of all projects will be collected. Interaction with the report
-// can be done via the Reporter instance
-func (t *Timetrace) Report(filter ...func(*Record) bool) (*Reporter, error) {
-	recordDirs, err := t.fs.RecordDirs()
+// If multiple parallel records are running, ErrMultipleRecordsRunning is
+// returned. Since records are identified by their start minute, switching
+// within the first minute of the running record isn't possible.
+func (t *Timetrace) Switch(projectKey string, tags []string, isBillable *bool, note string) error {
+	if err := t.ValidateTags(tags); err != nil {
+		return err
+	}
+
+	project, err := t.LoadProject(projectKey)
+	if err != nil {
+		return err
+	}
+
+	running, err := t.runningRecord("")
+	if err != nil {
+		return err
+	}
+
+	now := t.formatter.Now()
+
+	if sameRecordKey(running.Start, now) {
+		return ErrSwitchTooSoon
+	}
+
+	billable := t.projectBillable(projectKey)
+	if isBillable != nil {
+		billable = *isBillable
+	}
+
+	record := Record{
+		Start:      now,
+		Project:    project,
+		IsBillable: billable,
+		Tags:       tags,
+		Note:       note,
+	}
+
+	// Check that the new record can be saved before stopping the running
+	// record, so that a failing switch leaves the running record untouched.
+	if _, err := t.fs.LoadRecord(record.Start); err == nil {
+		return ErrRecordAlreadyExists
+	}
+
+	// Keep a copy of the running record, including its pauses modified by
+	// finishRecord, to restore it if the new record can't be saved.
+	original := *running
+	original.Pauses = append([]Pause(nil), running.Pauses...)
+
+	if err := t.finishRecord(running, now); err != nil {
+		return err
+	}
+
+	if err := t.SaveRecord(record, false); err != nil {
+		if restoreErr := t.restoreRecord(original, running); restoreErr != nil {
+			return fmt.Errorf("%w (failed to restore the running record: %s)", err, restoreErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// restoreRecord reverts finishRecord by saving the original record again and
+// deleting all parts of the finished record that have been stored as new
+// records when splitting it at midnight.
+func (t *Timetrace) restoreRecord(original Record, finished *Record) error {
+	if t.config.SplitAtMidnight {
+		for _, part := range finished.splitAtMidnight()[1:] {
+			if err := t.DeleteRecord(*part, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	return t.SaveRecord(original, true)
+}
+
+// finishRecord sets the end time of the record and saves it. If the record is
+// paused, the pause ends along with the record. If splitting records at
+// midnight is enabled, the record is saved as one record per day.
+func (t *Timetrace) finishRecord(record *Record, end time.Time) error {
+	record.End = &end
+
+	if record.IsPaused() {
+		record.Pauses[len(record.Pauses)-1].End = &end
+	}
+
+	if !t.config.SplitAtMidnight {
+		return t.SaveRecord(*record, true)
+	}
+
+	// The first part replaces the record, all other parts start at midnight
+	// and are stored as new records.
+	for i, part := range record.splitAtMidnight() {
+		if err := t.SaveRecord(*part, i == 0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Pause pauses the time tracking without stopping the current record. The time
+// until the record is resumed won't be included in the record's duration. The
+// project key works the same way as for Stop.
+func (t *Timetrace) Pause(projectKey string) error {
+	record, err := t.runningRecord(projectKey)
+	if err != nil {
+		return err
+	}
+
+	if record.IsPaused() {
+		return ErrAlreadyPaused
+	}
+
+	record.Pauses = append(record.Pauses, Pause{
+		Start: t.formatter.Now(),
+	})
+
+	return t.SaveRecord(*record, true)
+}
+
+// Resume resumes the time tracking for the current record after it has been
+// paused using Pause. The project key works the same way as for Stop.
+func (t *Timetrace) Resume(projectKey string) error {
+	record, err := t.runningRecord(projectKey)
+	if err != nil {
+		return err
+	}
+
+	if !record.IsPaused() {
+		return ErrNotPaused
+	}
+
+	end := t.formatter.Now()
+	record.Pauses[len(record.Pauses)-1].End = &end
+
+	return t.SaveRecord(*record, true)
+}
+
+// runningRecord returns the running record of the given project. If the project
+// key is empty, the only running record is returned. ErrMultipleRecordsRunning
+// is returned if the record to be used is ambiguous.
+func (t *Timetrace) runningRecord(projectKey string) (*Record, error) {
+	runningRecords, err := t.LoadRunningRecords()
 	if err != nil {
 		return nil, err
 	}
+
+	matchingRecords := make([]*Record, 0)
+
+	for _, record := range runningRecords {
+		if projectKey == "" || (record.Project != nil && record.Project.Key == projectKey) {
+			matchingRecords = append(matchingRecords, record)
+		}
+	}
+
+	switch len(matchingRecords) {
+	case 0:
+		return nil, ErrTrackingNotStarted
+	case 1:
+		return matchingRecords[0], nil
+	default:
+		return nil, ErrMultipleRecordsRunning
+	}
+}
+
+// Report generates a report of tracked times
+//
+// Only records that started within the given range are loaded from the store.
+// The range works just like the one of FilterByTimeRange, so both boundaries
+// are inclusive and a zero time disables the respective boundary.
+//
+// The report can be further filtered by the given Filter* funcs. Interaction
+// with the report can be done via the Reporter instance
+func (t *Timetrace) Report(from, to time.Time, filter ...func(*Record) bool) (*Reporter, error) {
+	filter = append([]func(*Record) bool{FilterByTimeRange(from, to)}, filter...)
+
 	// collect records
-	var result = make([]*Record, 0)
-	for _, dir := range recordDirs {
-		records, err := t.loadFromRecordDir(dir, filter...)
-		if err != nil {
-			return nil, err
+	loadFrom, loadTo := reportRange(from, to)
+
+	result, err := t.loadRecords(loadFrom, loadTo, filter...)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.newReporter(result)
+}
+
+// DailyReport works just like Report, but records spanning midnight are split
+// into one part per day before filtering them. This way, filtering by time
+// range and grouping by day only count the part of a record that belongs to the
+// respective day. The parts are not stored and must not be saved.
+func (t *Timetrace) DailyReport(from, to time.Time, filter ...func(*Record) bool) (*Reporter, error) {
+	filter = append([]func(*Record) bool{FilterByTimeRange(from, to)}, filter...)
+
+	// Records that started the day before the range may have a part within
+	// the range.
+	loadFrom, loadTo := reportRange(from, to)
+	if !loadFrom.IsZero() {
+		loadFrom = loadFrom.AddDate(0, 0, -1)
+	}
+
+	records, err := t.loadRecords(loadFrom, loadTo)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Record, 0, len(records))
+
+	for _, record := range records {
+	parts:
+		for _, part := range record.splitAtMidnight() {
+			for _, f := range filter {
+				if !f(part) {
+					continue parts
+				}
+			}
+			result = append(result, part)
 		}
-		result = append(result, records...)
 	}
 
+	return t.newReporter(result)
+}
+
+// reportRange converts the inclusive date range of a report into the range of
+// record keys to load. Just like for FilterByTimeRange, the end date is included
+// by adding one day.
+func reportRange(from, to time.Time) (time.Time, time.Time) {
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	return from, to
+}
+
+func (t *Timetrace) newReporter(result []*Record) (*Reporter, error) {
 	var reporter = Reporter{
-		t:      t,
-		report: make(map[string][]*Record),
-		totals: make(map[string]time.Duration),
+		t:         t,
+		report:    make(map[string][]*Record),
+		totals:    make(map[string]time.Duration),
+		rates:     make(map[string]hourlyRate),
+		amounts:   make(map[string]map[string]float64),
+		tagTotals: make(map[string]time.Duration),
+	}
+
+	for _, record := range result {
+		if _, ok := reporter.rates[record.Project.Key]; ok {
+			continue
+		}
+		rate, currency, err := t.ProjectRate(record.Project.Key)
+		if err != nil {
+			return nil, err
+		}
+		reporter.rates[record.Project.Key] = hourlyRate{rate: rate, currency: currency}
 	}
+
 	// prepare data  for serialization
 	reporter.sortAndMerge(result)
 	return &reporter, nil
@@ -222,6 +692,36 @@ func (t *Timetrace) EnsureDirectories() error {
 	return t.fs.EnsureDirectories()
 }
 
+// Lock acquires an advisory lock on the store, so that other timetrace processes
+// can't modify the store until Unlock is called. Callers should hold the lock
+// for the entire read-modify-write cycle of an operation. If another process
+// holds the lock for longer than the configured lock timeout, ErrStoreLocked
+// is returned.
+func (t *Timetrace) Lock() error {
+	timeout := t.config.LockTimeout
+	if timeout <= 0 {
+		timeout = defaultLockTimeout
+	}
+
+	err := t.fs.Lock(timeout)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return ErrStoreLocked
+	}
+	if err != nil {
+		return err
+	}
+
+	t.locked = true
+
+	return nil
+}
+
+// Unlock releases the lock acquired by Lock.
+func (t *Timetrace) Unlock() error {
+	t.locked = false
+	return t.fs.Unlock()
+}
+
 func (t *Timetrace) Config() *config.Config {
 	return t.config
 }
@@ -231,7 +731,7 @@ func (t *Timetrace) Formatter() *Formatter {
 }
 
 func (t *Timetrace) trackedTime(date time.Time) (time.Duration, error) {
-	records, err := t.loadAllRecords(date)
+	records, err := t.ListRecords(date)
 	if err != nil {
 		return 0, err
 	}
@@ -239,44 +739,12 @@ func (t *Timetrace) trackedTime(date time.Time) (time.Duration, error) {
 	var trackedTime time.Duration
 
 	for _, record := range records {
-		trackedTime += record.Duration()
+		trackedTime += record.DurationOn(date)
 	}
 
 	return trackedTime, nil
 }
 
-func (t *Timetrace) isDirEmpty(dir string) (bool, error) {
-	openedDir, err := os.Open(dir)
-	if err != nil {
-		return false, err
-	}
-	defer openedDir.Close()
-
-	// Attempt to read 1 file's name, if it fails with
-	// EOF, directory is empty
-	_, err = openedDir.Readdirnames(1)
-	if err == io.EOF {
-		return true, nil
-	}
-
-	return false, err
-}
-
-func (t *Timetrace) latestNonEmptyDir(dirs []string) (string, error) {
-	for i := len(dirs) - 1; i >= 0; i-- {
-		isEmpty, err := t.isDirEmpty(dirs[i])
-		if err != nil {
-			return "", err
-		}
-
-		if !isEmpty {
-			return dirs[i], nil
-		}
-	}
-
-	return "", ErrAllDirectoriesEmpty
-}
-
 func printCollisions(t *Timetrace, records []*Record) {
 	out.Err("collides with these records :")
 
@@ -311,22 +779,11 @@ func printCollisions(t *Timetrace, records []*Record) {
 // RecordCollides checks if the time of a record collides
 // with other records of the same day and returns a bool
 func (t *Timetrace) RecordCollides(toCheck Record) (bool, error) {
-	allRecords, err := t.loadAllRecords(toCheck.Start)
+	collide, collidingRecords, err := t.recordCollides(toCheck)
 	if err != nil {
 		return false, err
 	}
 
-	if toCheck.Start.Day() != toCheck.End.Day() {
-		moreRecords, err := t.loadAllRecords(*toCheck.End)
-		if err != nil {
-			return false, err
-		}
-		for _, rec := range moreRecords {
-			allRecords = append(allRecords, rec)
-		}
-	}
-
-	collide, collidingRecords := collides(toCheck, allRecords)
 	if collide {
 		printCollisions(t, collidingRecords)
 	}
@@ -334,10 +791,39 @@ func (t *Timetrace) RecordCollides(toCheck Record) (bool, error) {
 	return collide, nil
 }
 
+// recordCollides works like RecordCollides, but returns the colliding records
+// instead of printing them.
+func (t *Timetrace) recordCollides(toCheck Record) (bool, []*Record, error) {
+	allRecords, err := t.loadRecordsOverlapping(toCheck.Start, *toCheck.End)
+	if err != nil {
+		return false, nil, err
+	}
+
+	collide, collidingRecords := collides(toCheck, allRecords)
+
+	return collide, collidingRecords, nil
+}
+
+// collides checks if the given record overlaps with any of the other records.
+// Parallel records are allowed to overlap, so they never collide. However, a
+// record must not start after a running parallel record, since the parallel
+// record would no longer be found by LoadRunningRecords.
 func collides(toCheck Record, allRecords []*Record) (bool, []*Record) {
 	collide := false
 	collidingRecords := make([]*Record, 0)
+
+	if toCheck.IsParallel {
+		return collide, collidingRecords
+	}
+
 	for _, rec := range allRecords {
+		if rec.IsParallel {
+			if rec.End == nil && toCheck.Start.After(rec.Start) {
+				collidingRecords = append(collidingRecords, rec)
+				collide = true
+			}
+			continue
+		}
 
 		if rec.End != nil && rec.Start.Before(*toCheck.End) && rec.End.After(toCheck.Start) {
 			collidingRecords = append(collidingRecords, rec)
@@ -352,8 +838,3 @@ func collides(toCheck Record, allRecords []*Record) (bool, []*Record) {
 
 	return collide, collidingRecords
 }
-
-// isBackFile checks if a given filename is a backup-file
-func isBakFile(filename string) bool {
-	return filepath.Ext(filename) == BakFileExt
-}
@@ -1,8 +1,13 @@
 package core
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/dominikbraun/timetrace/config"
+	"github.com/dominikbraun/timetrace/fs"
 )
 
 func newTestRecord(s int, e int) Record {
@@ -213,3 +218,523 @@ func TestFormatDuration(t *testing.T) {
 		}
 	}
 }
+
+func TestCollidesParallel(t *testing.T) {
+	savedRec := newTestRecord(-60, -1)
+	savedRecParallel := newTestRecord(-60, -1)
+	savedRecParallel.IsParallel = true
+
+	// rec1 is a parallel record inside savedRec
+	rec1 := newTestRecord(-40, -20)
+	rec1.IsParallel = true
+
+	if collide, _ := collides(rec1, []*Record{&savedRec}); collide {
+		t.Error("parallel records should not collide")
+	}
+
+	// rec2 is a regular record inside savedRecParallel
+	rec2 := newTestRecord(-40, -20)
+
+	if collide, _ := collides(rec2, []*Record{&savedRecParallel}); collide {
+		t.Error("records should not collide with parallel records")
+	}
+
+	// rec3 is a regular record inside both savedRec and savedRecParallel
+	rec3 := newTestRecord(-40, -20)
+
+	if collide, collidingRecs := collides(rec3, []*Record{&savedRec, &savedRecParallel}); !collide {
+		t.Error("records should collide")
+	} else {
+		checkConsistent(t, []*Record{&savedRec}, collidingRecs)
+	}
+
+	// rec4 is a regular record starting after a running parallel record, which
+	// would hide the parallel record from LoadRunningRecords
+	runningParallel := newTestRecord(-120, -1)
+	runningParallel.End = nil
+	runningParallel.IsParallel = true
+	rec4 := newTestRecord(-60, -30)
+
+	if collide, collidingRecs := collides(rec4, []*Record{&runningParallel}); !collide {
+		t.Error("records should collide with running parallel records started before them")
+	} else {
+		checkConsistent(t, []*Record{&runningParallel}, collidingRecs)
+	}
+
+	// rec5 is a regular record starting before the running parallel record
+	rec5 := newTestRecord(-180, -60)
+
+	if collide, _ := collides(rec5, []*Record{&runningParallel}); collide {
+		t.Error("records should not collide with running parallel records started after them")
+	}
+}
+
+// newMemoryTimetrace returns a Timetrace instance storing its resources in a
+// memoryFs with a single project called make-coffee.
+func newMemoryTimetrace(t *testing.T) *Timetrace {
+	store := &memoryFs{
+		projects: make(map[string][]byte),
+		records:  make(map[int64][]byte),
+		backups:  make(map[int64][]byte),
+	}
+	tt := &Timetrace{
+		config:    &config.Config{},
+		fs:        &journalingFs{Filesystem: store, location: time.Local},
+		formatter: &Formatter{},
+	}
+
+	if err := tt.SaveProject(Project{Key: "make-coffee"}, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	return tt
+}
+
+func TestStartAndStopAt(t *testing.T) {
+	now := time.Now().Truncate(time.Minute)
+
+	tests := map[string]struct {
+		previous    *Record
+		start       time.Time
+		end         time.Time
+		parallel    bool
+		expectedErr error
+	}{
+		"back-dated": {
+			start: now.Add(-30 * time.Minute),
+			end:   now.Add(-10 * time.Minute),
+		},
+		"after previous record": {
+			previous: &Record{Start: now.Add(-time.Hour), End: timePtr(now.Add(-30 * time.Minute))},
+			start:    now.Add(-30 * time.Minute),
+		},
+		"overlapping previous record": {
+			previous:    &Record{Start: now.Add(-time.Hour), End: timePtr(now.Add(-20 * time.Minute))},
+			start:       now.Add(-30 * time.Minute),
+			expectedErr: ErrOverlapsPrevious,
+		},
+		"colliding with later record": {
+			previous:    &Record{Start: now.Add(-20 * time.Minute), End: timePtr(now.Add(-10 * time.Minute))},
+			start:       now.Add(-30 * time.Minute),
+			expectedErr: ErrRecordCollides,
+		},
+		"parallel after previous record": {
+			previous: &Record{Start: now.Add(-time.Hour)},
+			start:    now.Add(-30 * time.Minute),
+			parallel: true,
+		},
+		"parallel before previous record": {
+			previous:    &Record{Start: now.Add(-time.Hour), End: timePtr(now.Add(-30 * time.Minute))},
+			start:       now.Add(-4 * time.Hour),
+			parallel:    true,
+			expectedErr: ErrParallelTooEarly,
+		},
+		"future start": {
+			start:       now.Add(time.Hour),
+			expectedErr: ErrFutureTime,
+		},
+		"future end": {
+			start:       now.Add(-30 * time.Minute),
+			end:         now.Add(time.Hour),
+			expectedErr: ErrFutureTime,
+		},
+		"end before start": {
+			start:       now.Add(-30 * time.Minute),
+			end:         now.Add(-40 * time.Minute),
+			expectedErr: ErrEndBeforeStart,
+		},
+	}
+
+	for name, tc := range tests {
+		tt := newMemoryTimetrace(t)
+
+		if tc.previous != nil {
+			tc.previous.Project = &Project{Key: "make-coffee"}
+			if err := tt.SaveRecord(*tc.previous, false); err != nil {
+				t.Fatalf("%s: unexpected error: %s", name, err)
+			}
+		}
+
+		err := tt.Start("make-coffee", false, nil, tc.parallel, "", tc.start)
+		if err == nil && tc.parallel {
+			// The parallel record must be found among the running records,
+			// otherwise it can't be stopped anymore.
+			var running []*Record
+			if running, err = tt.LoadRunningRecords(); err == nil &&
+				(len(running) == 0 || !running[len(running)-1].Start.Equal(tc.start)) {
+				t.Errorf("%s: expected the parallel record to be running, got %v", name, running)
+			}
+		}
+		if err == nil && !tc.end.IsZero() {
+			err = tt.Stop("", tc.end)
+		}
+		if !errors.Is(err, tc.expectedErr) {
+			t.Fatalf("%s: expected error %v, got %v", name, tc.expectedErr, err)
+		}
+		if tc.expectedErr != nil {
+			continue
+		}
+
+		record, err := tt.LoadRecord(tc.start)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+		if !record.Start.Equal(tc.start) {
+			t.Errorf("%s: expected start %s, got %s", name, tc.start, record.Start)
+		}
+		if !tc.end.IsZero() && (record.End == nil || !record.End.Equal(tc.end)) {
+			t.Errorf("%s: expected end %s, got %v", name, tc.end, record.End)
+		}
+	}
+}
+
+func TestStartWithNote(t *testing.T) {
+	tt := newMemoryTimetrace(t)
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	note := "Descale the machine"
+
+	if err := tt.Start("make-coffee", false, nil, false, note, start); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := tt.Stop("", time.Time{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	record, err := tt.LoadRecord(start)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if record.Note != note {
+		t.Errorf("expected note %q, got %q", note, record.Note)
+	}
+
+	reporter, err := tt.Report(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	rows, _ := reporter.Table()
+	if len(rows) == 0 || rows[0][6] != note {
+		t.Errorf("expected the note in the report table, got %v", rows)
+	}
+
+	edited := "Descale and clean the machine"
+	if err := tt.EditRecord(start, RecordChanges{Note: &edited}, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if record, err = tt.LoadRecord(start); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if record.Note != edited {
+		t.Errorf("expected edited note %q, got %q", edited, record.Note)
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestSwitch(t *testing.T) {
+	tests := map[string]struct {
+		runningSince     time.Duration
+		sameMinute       bool
+		isBillable       *bool
+		expectedBillable bool
+		expectedErr      error
+	}{
+		"billable by default": {
+			runningSince:     30 * time.Minute,
+			expectedBillable: true,
+		},
+		"non-billable": {
+			runningSince: 30 * time.Minute,
+			isBillable:   new(bool),
+		},
+		"too soon": {
+			sameMinute:  true,
+			expectedErr: ErrSwitchTooSoon,
+		},
+	}
+
+	for name, tc := range tests {
+		tt := newMemoryTimetrace(t)
+		tt.config.Projects = map[string]config.Project{"clean-kitchen": {Billable: true}}
+
+		if err := tt.SaveProject(Project{Key: "clean-kitchen"}, false); err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+
+		start := time.Now().Add(-tc.runningSince)
+		if tc.sameMinute {
+			// Start the running record at the beginning of the current minute,
+			// waiting for the next one if the switch might not happen within
+			// the current minute anymore.
+			if left := start.Truncate(time.Minute).Add(time.Minute).Sub(start); left < 5*time.Second {
+				time.Sleep(left)
+			}
+			start = time.Now().Truncate(time.Minute)
+		}
+
+		running := Record{Start: start, Project: &Project{Key: "make-coffee"}}
+		if err := tt.SaveRecord(running, false); err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+
+		err := tt.Switch("clean-kitchen", []string{"dishes"}, tc.isBillable, "")
+		if !errors.Is(err, tc.expectedErr) {
+			t.Fatalf("%s: expected error %v, got %v", name, tc.expectedErr, err)
+		}
+		if tc.expectedErr != nil {
+			continue
+		}
+
+		stopped, err := tt.LoadRecord(running.Start)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+
+		current, err := tt.LoadLatestRecord()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+
+		if stopped.End == nil || !stopped.End.Equal(current.Start) {
+			t.Errorf("%s: expected the stopped record to end at %s, got %v", name, current.Start, stopped.End)
+		}
+		if current.End != nil || current.Project.Key != "clean-kitchen" || len(current.Tags) != 1 {
+			t.Errorf("%s: unexpected current record %+v", name, current)
+		}
+		if current.IsBillable != tc.expectedBillable {
+			t.Errorf("%s: expected billable %t, got %t", name, tc.expectedBillable, current.IsBillable)
+		}
+	}
+}
+
+// failingFs is a memoryFs that fails to save records started after a given
+// time.
+type failingFs struct {
+	*memoryFs
+	after time.Time
+}
+
+func (f *failingFs) SaveRecord(start time.Time, data []byte) error {
+	if start.After(f.after) {
+		return errors.New("disk full")
+	}
+	return f.memoryFs.SaveRecord(start, data)
+}
+
+func TestSwitchRestoresRunningRecord(t *testing.T) {
+	tt := newMemoryTimetrace(t)
+
+	running := Record{
+		Start:   time.Now().Add(-30 * time.Minute),
+		Project: &Project{Key: "make-coffee"},
+		Pauses:  []Pause{{Start: time.Now().Add(-10 * time.Minute)}},
+	}
+	if err := tt.SaveRecord(running, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Saving the new record fails, while the running record can still be
+	// saved.
+	journal := tt.fs.(*journalingFs)
+	journal.Filesystem = &failingFs{memoryFs: journal.Filesystem.(*memoryFs), after: running.Start}
+
+	if err := tt.Switch("make-coffee", nil, nil, ""); err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+
+	restored, err := tt.LoadLatestRecord()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !sameRecordKey(restored.Start, running.Start) || restored.End != nil {
+		t.Errorf("expected the running record to be restored, got %+v", restored)
+	}
+	if len(restored.Pauses) != 1 || restored.Pauses[0].End != nil {
+		t.Errorf("expected the pause to be restored, got %+v", restored.Pauses)
+	}
+}
+
+func TestContinue(t *testing.T) {
+	tt := newMemoryTimetrace(t)
+
+	end := time.Now().Add(-time.Hour)
+	previous := Record{
+		Start:      end.Add(-time.Hour),
+		End:        &end,
+		Project:    &Project{Key: "make-coffee"},
+		IsBillable: true,
+		Tags:       []string{"espresso"},
+		Note:       "Descale the machine",
+	}
+
+	if err := tt.SaveRecord(previous, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := tt.Continue(previous); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	current, err := tt.LoadLatestRecord()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if current.Start.Before(end) || current.End != nil {
+		t.Errorf("expected a new running record, got %+v", current)
+	}
+	if current.Project.Key != previous.Project.Key || !current.IsBillable || current.Note != previous.Note ||
+		len(current.Tags) != 1 || current.Tags[0] != "espresso" {
+		t.Errorf("expected the record to be cloned, got %+v", current)
+	}
+
+	if err := tt.Continue(previous); !errors.Is(err, ErrNoEndTime) {
+		t.Errorf("expected error %v, got %v", ErrNoEndTime, err)
+	}
+}
+
+func TestContinueAfterStop(t *testing.T) {
+	tt := newMemoryTimetrace(t)
+
+	// Wait for the next minute if continuing might not happen within the
+	// current minute anymore.
+	now := time.Now()
+	if left := now.Truncate(time.Minute).Add(time.Minute).Sub(now); left < 5*time.Second {
+		time.Sleep(left)
+	}
+
+	if err := tt.Start("make-coffee", false, nil, false, "", time.Time{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	running, err := tt.LoadLatestRecord()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err = tt.Continue(*running)
+	if !errors.Is(err, ErrNoEndTime) {
+		t.Fatalf("expected error %v, got %v", ErrNoEndTime, err)
+	}
+	if key := tt.formatter.RecordKey(running); !strings.Contains(err.Error(), key) {
+		t.Errorf("expected the error to name the running record %s, got %q", key, err)
+	}
+
+	if err := tt.Stop("", time.Time{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := tt.Continue(*running); !errors.Is(err, ErrContinueTooSoon) {
+		t.Errorf("expected error %v, got %v", ErrContinueTooSoon, err)
+	}
+}
+
+func TestStopRecord(t *testing.T) {
+	tt := newMemoryTimetrace(t)
+
+	now := time.Now().Truncate(time.Minute)
+	starts := []time.Time{now.Add(-time.Hour), now.Add(-30 * time.Minute)}
+
+	for _, start := range starts {
+		record := Record{Start: start, Project: &Project{Key: "make-coffee"}, IsParallel: true}
+		if err := tt.SaveRecord(record, false); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if err := tt.Stop("make-coffee", time.Time{}); !errors.Is(err, ErrMultipleRecordsRunning) {
+		t.Fatalf("expected error %v, got %v", ErrMultipleRecordsRunning, err)
+	}
+
+	if err := tt.StopRecord(starts[0], time.Time{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := tt.StopRecord(starts[0], time.Time{}); !errors.Is(err, ErrRecordNotRunning) {
+		t.Errorf("expected error %v, got %v", ErrRecordNotRunning, err)
+	}
+
+	// With only one record of the project left, it can be stopped by project.
+	if err := tt.Stop("make-coffee", time.Time{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	running, err := tt.LoadRunningRecords()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(running) != 0 {
+		t.Errorf("expected no running records, got %d", len(running))
+	}
+}
+
+func TestDailyReport(t *testing.T) {
+	tt := newMemoryTimetrace(t)
+
+	day := time.Date(2021, 06, 07, 0, 00, 00, 00, time.Local)
+	at := func(days, hours int) time.Time {
+		return day.AddDate(0, 0, days).Add(time.Duration(hours) * time.Hour)
+	}
+
+	records := []Record{
+		{Start: at(-2, 10), End: timePtr(at(-2, 11))},
+		{Start: at(-1, 22), End: timePtr(at(0, 2))},
+		{Start: at(0, 10), End: timePtr(at(0, 11))},
+		{Start: at(1, 10), End: timePtr(at(1, 11))},
+	}
+
+	for _, record := range records {
+		record.Project = &Project{Key: "make-coffee"}
+		if err := tt.SaveRecord(record, false); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	reporter, err := tt.DailyReport(day, day)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The record spanning midnight counts with its part after midnight.
+	var total time.Duration
+	for _, record := range reporter.report["make-coffee"] {
+		total += record.Duration()
+	}
+
+	if total != 3*time.Hour {
+		t.Errorf("expected 3h on %s, got %s", day.Format(dateLayout), total)
+	}
+}
+
+func TestLock(t *testing.T) {
+	c := &config.Config{Store: t.TempDir(), LockTimeout: 100 * time.Millisecond}
+
+	// Each instance locks the store just like a separate timetrace process.
+	first, second := New(c, fs.New(c)), New(c, fs.New(c))
+
+	if err := first.Lock(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := second.Lock(); !errors.Is(err, ErrStoreLocked) {
+		t.Fatalf("expected error %v, got %v", ErrStoreLocked, err)
+	}
+
+	if err := first.Unlock(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := second.Lock(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := second.Unlock(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
@@ -1,15 +1,18 @@
 package fs
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/dominikbraun/timetrace/config"
 )
@@ -18,7 +21,9 @@ const (
 	rootDirName     = ".timetrace"
 	projectsDirName = "projects"
 	recordsDirName  = "records"
+	tagsDirName     = "tags"
 	reportDirName   = "reports"
+	journalFileName = "journal.jsonl"
 )
 
 const (
@@ -27,156 +32,501 @@ const (
 	recordBackupFilepathLayout = "15-04.json.bak"
 )
 
+const (
+	projectFileExt       = ".json"
+	projectBackupFileExt = ".json.bak"
+	tagFileExt           = ".json"
+	backupFileExt        = ".bak"
+	tempFileExt          = ".tmp"
+)
+
+// journalChunkSize is the number of bytes read at once when searching the
+// journal file for its last entry.
+const journalChunkSize = 4096
+
+// Fs is a storage backend that stores each project and record as a JSON file
+// in a directory tree. Records are grouped into one directory per day.
+//
+// Record directories and file names are formatted in the home time zone,
+// regardless of the time zone of the given start time, so that a record is
+// always filed under the same day.
 type Fs struct {
 	config    *config.Config
 	sanitizer *strings.Replacer
+	storeLock fileLock
+	location  *time.Location
 }
 
 func New(config *config.Config) *Fs {
+	location, err := config.Location()
+	if err != nil {
+		location = time.Local
+	}
+
 	return &Fs{
 		config:    config,
 		sanitizer: strings.NewReplacer("/", "-", "\\", "-"),
+		location:  location,
 	}
 }
 
-// ProjectFilepath returns the filepath of the project with the given key.
-func (fs *Fs) ProjectFilepath(key string) string {
-	key = fs.sanitizer.Replace(key)
-	name := fmt.Sprintf("%s.json", key)
-	return filepath.Join(fs.projectsDir(), name)
+// ProjectKeys returns the keys of all non-backup projects sorted alphabetically.
+func (fs *Fs) ProjectKeys() ([]string, error) {
+	return fs.projectKeys(false)
+}
+
+// ProjectBackupKeys returns the keys of all backup projects sorted alphabetically.
+func (fs *Fs) ProjectBackupKeys() ([]string, error) {
+	return fs.projectKeys(true)
 }
 
-// ProjectBackupFilepath return the filepath of the backup project with the
+// LoadProject returns the stored data of the project with the given key. If
+// the project doesn't exist, an error wrapping os.ErrNotExist is returned.
+func (fs *Fs) LoadProject(key string) ([]byte, error) {
+	return ioutil.ReadFile(fs.projectFilepath(key))
+}
+
+// LoadProjectBackup returns the stored data of the backup project with the
 // given key.
-func (fs *Fs) ProjectBackupFilepath(key string) string {
-	key = fs.sanitizer.Replace(key)
-	name := fmt.Sprintf("%s.json.bak", key)
-	return filepath.Join(fs.projectsDir(), name)
+func (fs *Fs) LoadProjectBackup(key string) ([]byte, error) {
+	return ioutil.ReadFile(fs.projectBackupFilepath(key))
 }
 
-// ProjectFilepaths returns all non-backup project filepaths sorted alphabetically.
-func (fs *Fs) ProjectFilepaths() ([]string, error) {
-	dir := fs.projectsDir()
+// SaveProject stores the data of the project with the given key, replacing any
+// existing project with the same key.
+func (fs *Fs) SaveProject(key string, data []byte) error {
+	return writeFileAtomic(fs.projectFilepath(key), data, 0600)
+}
 
-	items, err := ioutil.ReadDir(dir)
+// SaveProjectBackup stores the data of the backup project with the given key.
+func (fs *Fs) SaveProjectBackup(key string, data []byte) error {
+	return writeFileAtomic(fs.projectBackupFilepath(key), data, 0600)
+}
+
+// DeleteProject removes the project with the given key. Its backup is kept.
+func (fs *Fs) DeleteProject(key string) error {
+	return os.Remove(fs.projectFilepath(key))
+}
+
+// RecordKeys returns the start times of all non-backup records that started
+// within the given range, sorted from oldest to newest. The lower boundary is
+// inclusive, the upper boundary is exclusive. A zero time disables the
+// respective boundary.
+//
+// Since records are stored with minute precision, the returned start times
+// don't have seconds.
+func (fs *Fs) RecordKeys(from, to time.Time) ([]time.Time, error) {
+	return fs.recordKeys(from, to, false)
+}
+
+// RecordBackupKeys works like RecordKeys but returns the start times of all
+// backup records.
+func (fs *Fs) RecordBackupKeys(from, to time.Time) ([]time.Time, error) {
+	return fs.recordKeys(from, to, true)
+}
+
+// LatestRecordKey returns the start time of the youngest non-backup record. If
+// there are no records at all, the zero time is returned.
+func (fs *Fs) LatestRecordKey() (time.Time, error) {
+	dirs, err := fs.recordDirs()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// Walk the directories from newest to oldest and stop as soon as a
+	// directory containing at least one record is found.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		keys, err := fs.recordKeysFromDir(dirs[i], false)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if len(keys) > 0 {
+			return keys[len(keys)-1], nil
+		}
+	}
+
+	return time.Time{}, nil
+}
+
+// LoadRecord returns the stored data of the record with the given start time.
+// If the record doesn't exist, an error wrapping os.ErrNotExist is returned.
+func (fs *Fs) LoadRecord(start time.Time) ([]byte, error) {
+	return ioutil.ReadFile(fs.recordFilepath(start))
+}
+
+// LoadRecordBackup returns the stored data of the backup record with the given
+// start time.
+func (fs *Fs) LoadRecordBackup(start time.Time) ([]byte, error) {
+	return ioutil.ReadFile(fs.recordBackupFilepath(start))
+}
+
+// SaveRecord stores the data of the record with the given start time,
+// replacing any existing record with the same start time.
+func (fs *Fs) SaveRecord(start time.Time, data []byte) error {
+	if err := fs.ensureRecordDir(start); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(fs.recordFilepath(start), data, 0600)
+}
+
+// SaveRecordBackup stores the data of the backup record with the given start
+// time.
+func (fs *Fs) SaveRecordBackup(start time.Time, data []byte) error {
+	if err := fs.ensureRecordDir(start); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(fs.recordBackupFilepath(start), data, 0600)
+}
+
+// DeleteRecord removes the record with the given start time. Its backup is
+// kept.
+func (fs *Fs) DeleteRecord(start time.Time) error {
+	return os.Remove(fs.recordFilepath(start))
+}
+
+// TagKeys returns the keys of all registered tags sorted alphabetically.
+func (fs *Fs) TagKeys() ([]string, error) {
+	items, err := ioutil.ReadDir(fs.tagsDir())
 	if err != nil {
 		return nil, err
 	}
 
-	var filepaths []string
+	var keys []string
 
 	for _, item := range items {
-		if item.IsDir() {
+		if item.IsDir() || filepath.Ext(item.Name()) != tagFileExt {
 			continue
 		}
-		itemName := item.Name()
-		if strings.HasSuffix(itemName, ".bak") {
+		keys = append(keys, strings.TrimSuffix(item.Name(), tagFileExt))
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// LoadTag returns the stored data of the tag with the given key. If the tag
+// doesn't exist, an error wrapping os.ErrNotExist is returned.
+func (fs *Fs) LoadTag(key string) ([]byte, error) {
+	return ioutil.ReadFile(fs.tagFilepath(key))
+}
+
+// SaveTag stores the data of the tag with the given key, replacing any existing
+// tag with the same key.
+func (fs *Fs) SaveTag(key string, data []byte) error {
+	return writeFileAtomic(fs.tagFilepath(key), data, 0600)
+}
+
+// DeleteTag removes the tag with the given key.
+func (fs *Fs) DeleteTag(key string) error {
+	return os.Remove(fs.tagFilepath(key))
+}
+
+// EnsureDirectories creates all required timetrace directories. If they already
+// exist, nothing happens.
+func (fs *Fs) EnsureDirectories() error {
+	dirs := []string{
+		fs.projectsDir(),
+		fs.recordsDir(),
+		fs.recordsInitSubDir(),
+		fs.tagsDir(),
+		fs.ReportDir(),
+	}
+
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// JournalEntries returns the data of all journal entries in the order they have
+// been appended. The journal is stored as a file containing one entry per line.
+func (fs *Fs) JournalEntries() ([][]byte, error) {
+	data, err := ioutil.ReadFile(fs.journalFilepath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries [][]byte
+
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
 			continue
 		}
+		entries = append(entries, line)
+	}
+
+	return entries, nil
+}
+
+// LatestJournalEntry returns the data of the latest journal entry, or nil if
+// the journal is empty. The journal file is read backwards from its end until
+// the start of the last line has been found.
+func (fs *Fs) LatestJournalEntry() ([]byte, error) {
+	file, err := os.Open(fs.journalFilepath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var tail []byte
+
+	for end := info.Size(); end > 0; {
+		size := int64(journalChunkSize)
+		if size > end {
+			size = end
+		}
+		end -= size
+
+		chunk := make([]byte, size)
+		if _, err := file.ReadAt(chunk, end); err != nil {
+			return nil, err
+		}
+		tail = append(chunk, tail...)
+
+		line := bytes.TrimRightFunc(tail, unicode.IsSpace)
+		if i := bytes.LastIndexByte(line, '\n'); i >= 0 {
+			return line[i+1:], nil
+		}
+	}
 
-		filepaths = append(filepaths, filepath.Join(dir, itemName))
+	if line := bytes.TrimSpace(tail); len(line) > 0 {
+		return line, nil
 	}
-	sort.Strings(filepaths)
 
-	return filepaths, nil
+	return nil, nil
 }
 
-// ProjectBackupFilepaths returns all backup project filepaths sorted alphabetically.
-func (fs *Fs) ProjectBackupFilepaths() ([]string, error) {
-	dir := fs.projectsDir()
+// AppendJournalEntry appends the data of a journal entry to the journal. The
+// data must not contain any newlines.
+func (fs *Fs) AppendJournalEntry(data []byte) error {
+	if bytes.ContainsRune(data, '\n') {
+		return fmt.Errorf("journal entry must not contain newlines")
+	}
 
-	items, err := ioutil.ReadDir(dir)
+	file, err := os.OpenFile(fs.journalFilepath(), os.O_APPEND|os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+
+	line := append(data, '\n')
+
+	// If a previous entry has been written partially, it is terminated so
+	// that it doesn't corrupt the new entry.
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			line = append([]byte{'\n'}, line...)
+		}
+	}
+
+	if _, err := file.Write(line); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Lock acquires an advisory lock on the file tree, waiting for other processes
+// to release the lock until the timeout has passed. If the lock can't be
+// acquired in time, an error wrapping os.ErrDeadlineExceeded is returned.
+func (fs *Fs) Lock(timeout time.Duration) error {
+	fs.storeLock.path = filepath.Join(fs.rootDir(), lockFileName)
+	return fs.storeLock.lock(timeout)
+}
+
+// Unlock releases the lock acquired by Lock.
+func (fs *Fs) Unlock() error {
+	return fs.storeLock.unlock()
+}
+
+func (fs *Fs) ReportDir() string {
+	return path.Join(fs.rootDir(), reportDirName)
+}
+
+func (fs *Fs) WriteReport(filepath string, data []byte) error {
+	return writeReport(filepath, fs.config.ReportPath, fs.ReportDir(), data)
+}
+
+// projectFilepath returns the filepath of the project with the given key.
+func (fs *Fs) projectFilepath(key string) string {
+	key = fs.sanitizer.Replace(key)
+	name := fmt.Sprintf("%s%s", key, projectFileExt)
+	return filepath.Join(fs.projectsDir(), name)
+}
+
+// projectBackupFilepath return the filepath of the backup project with the
+// given key.
+func (fs *Fs) projectBackupFilepath(key string) string {
+	key = fs.sanitizer.Replace(key)
+	name := fmt.Sprintf("%s%s", key, projectBackupFileExt)
+	return filepath.Join(fs.projectsDir(), name)
+}
+
+// tagFilepath returns the filepath of the tag with the given key.
+func (fs *Fs) tagFilepath(key string) string {
+	key = fs.sanitizer.Replace(key)
+	name := fmt.Sprintf("%s%s", key, tagFileExt)
+	return filepath.Join(fs.tagsDir(), name)
+}
+
+// projectKeys returns the keys of all project files sorted alphabetically. If
+// backup is set, only backup projects are considered, otherwise only
+// non-backup projects are considered.
+func (fs *Fs) projectKeys(backup bool) ([]string, error) {
+	items, err := ioutil.ReadDir(fs.projectsDir())
 	if err != nil {
 		return nil, err
 	}
 
-	var filepaths []string
+	var keys []string
 
 	for _, item := range items {
 		if item.IsDir() {
 			continue
 		}
 		itemName := item.Name()
-		if !strings.HasSuffix(itemName, ".bak") {
+		if isBakFile(itemName) != backup || isTempFile(itemName) {
 			continue
 		}
 
-		filepaths = append(filepaths, filepath.Join(dir, itemName))
+		ext := projectFileExt
+		if backup {
+			ext = projectBackupFileExt
+		}
+
+		keys = append(keys, strings.TrimSuffix(itemName, ext))
 	}
-	sort.Strings(filepaths)
+	sort.Strings(keys)
 
-	return filepaths, nil
+	return keys, nil
 }
 
-// RecordFilepath returns the filepath of the record with the given start time.
+// recordFilepath returns the filepath of the record with the given start time.
 //
 // Note that the start time also has to contain the date as this determines the
 // directory the project is stored in.
-func (fs *Fs) RecordFilepath(start time.Time) string {
-	name := start.Format(recordFilepathLayout)
-	return filepath.Join(fs.RecordDirFromDate(start), name)
+func (fs *Fs) recordFilepath(start time.Time) string {
+	name := start.In(fs.location).Format(recordFilepathLayout)
+	return filepath.Join(fs.recordDirFromDate(start), name)
 }
 
-func (fs *Fs) RecordBackupFilepath(start time.Time) string {
-	name := start.Format(recordBackupFilepathLayout)
-	return filepath.Join(fs.RecordDirFromDate(start), name)
+func (fs *Fs) recordBackupFilepath(start time.Time) string {
+	name := start.In(fs.location).Format(recordBackupFilepathLayout)
+	return filepath.Join(fs.recordDirFromDate(start), name)
 }
 
-// RecordFilepaths returns all non-backup record filepaths within the given
-// directory sorted by the given function.
-//
-// The directory can be obtained using functions like recordDir or RecordDirs.
-// If you have a record date, use RecordDirFromDate to get the directory name.
-//
-// The less function allows you to sort the records. Assume three record files:
-//
-//	- timetrace/records/2021-05-01/08-00.json
-//	- timetrace/records/2021-05-01/10-00.json
-//	- timetrace/records/2021-05-01/11-30.json
-//
-// The following call to RecordFilepaths will return the paths of those records
-// sorted from newest to oldest:
-//
-//	latestRecords, err := RecordFilepaths(dir, func (a, b string) bool {
-//		timeA, _ := time.Parse("15-04.json", a)
-//		timeB, _ := time.Parse("15-04.json", b)
-//		return timeA.Before(timeB)
-//	})
-//
-// This can be used to determine the latest record in a given record directory.
-func (fs *Fs) RecordFilepaths(dir string, less func(a, b string) bool) ([]string, error) {
+// recordKeys returns the start times of all records in the given range. The
+// record directories are filtered by their date first, so that only those
+// directories that may contain matching records are read.
+func (fs *Fs) recordKeys(from, to time.Time, backup bool) ([]time.Time, error) {
+	dirs, err := fs.recordDirs()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]time.Time, 0)
+
+	for _, dir := range dirs {
+		date, err := time.ParseInLocation(recordDirLayout, filepath.Base(dir), fs.location)
+		if err != nil {
+			continue
+		}
+
+		if !from.IsZero() && !date.AddDate(0, 0, 1).After(from) {
+			continue
+		}
+
+		if !to.IsZero() && !date.Before(to) {
+			continue
+		}
+
+		dirKeys, err := fs.recordKeysFromDir(dir, backup)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range dirKeys {
+			if !from.IsZero() && key.Before(from) {
+				continue
+			}
+			if !to.IsZero() && !key.Before(to) {
+				continue
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// recordKeysFromDir returns the start times of all records within the given
+// directory sorted from oldest to newest.
+func (fs *Fs) recordKeysFromDir(dir string, backup bool) ([]time.Time, error) {
 	items, err := ioutil.ReadDir(dir)
 	if err != nil {
 		return nil, err
 	}
 
-	filepaths := make([]string, 0)
+	layout := recordFilepathLayout
+	if backup {
+		layout = recordBackupFilepathLayout
+	}
+
+	keys := make([]time.Time, 0)
 
 	for _, item := range items {
 		if item.IsDir() {
 			continue
 		}
 		itemName := item.Name()
-		if strings.HasSuffix(itemName, ".bak") {
+		if isBakFile(itemName) != backup {
 			continue
 		}
 
-		filepaths = append(filepaths, filepath.Join(dir, itemName))
+		key, err := time.ParseInLocation(recordDirLayout+"/"+layout, filepath.Base(dir)+"/"+itemName, fs.location)
+		if err != nil {
+			continue
+		}
+
+		keys = append(keys, key)
 	}
 
-	sort.Slice(filepaths, func(i, j int) bool {
-		return less(filepaths[i], filepaths[j])
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Before(keys[j])
 	})
 
-	return filepaths, nil
+	return keys, nil
 }
 
-// RecordDirs returns all record directories sorted alphabetically. This can be
+// recordDirs returns all record directories sorted alphabetically. This can be
 // used to determine the latest record directory and obtain the latest record
-// within that directory using RecordFilepaths.
+// within that directory.
 //
-// Note that all timetrace directories have to exist for RecordDirs to work.
-func (fs *Fs) RecordDirs() ([]string, error) {
+// Note that all timetrace directories have to exist for recordDirs to work.
+func (fs *Fs) recordDirs() ([]string, error) {
 	items, err := ioutil.ReadDir(fs.recordsDir())
 	if err != nil {
 		return nil, err
@@ -196,32 +546,13 @@ func (fs *Fs) RecordDirs() ([]string, error) {
 	return dirs, nil
 }
 
-func (fs *Fs) RecordDirFromDate(date time.Time) string {
-	dir := date.Format(recordDirLayout)
+func (fs *Fs) recordDirFromDate(date time.Time) string {
+	dir := date.In(fs.location).Format(recordDirLayout)
 	return fs.recordDir(dir)
 }
 
-// EnsureDirectories creates all required timetrace directories. If they already
-// exist, nothing happens.
-func (fs *Fs) EnsureDirectories() error {
-	dirs := []string{
-		fs.projectsDir(),
-		fs.recordsDir(),
-		fs.recordsInitSubDir(),
-		fs.ReportDir(),
-	}
-
-	for _, dir := range dirs {
-		if err := os.MkdirAll(dir, 0777); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-func (fs *Fs) EnsureRecordDir(date time.Time) error {
-	return os.MkdirAll(fs.RecordDirFromDate(date), 0777)
+func (fs *Fs) ensureRecordDir(date time.Time) error {
+	return os.MkdirAll(fs.recordDirFromDate(date), 0777)
 }
 
 func (fs *Fs) recordDir(name string) string {
@@ -236,39 +567,127 @@ func (fs *Fs) recordsDir() string {
 	return filepath.Join(fs.rootDir(), recordsDirName)
 }
 
+func (fs *Fs) tagsDir() string {
+	return filepath.Join(fs.rootDir(), tagsDirName)
+}
+
 func (fs *Fs) recordsInitSubDir() string {
-	return fs.RecordDirFromDate(time.Now())
+	return fs.recordDirFromDate(time.Now())
 }
 
-func (fs *Fs) ReportDir() string {
-	return path.Join(fs.rootDir(), reportDirName)
+func (fs *Fs) journalFilepath() string {
+	return filepath.Join(fs.rootDir(), journalFileName)
 }
 
 func (fs *Fs) rootDir() string {
-	if fs.config.Store != "" {
-		return os.ExpandEnv(fs.config.Store)
+	if _, location := fs.config.StoreLocation(); location != "" {
+		return os.ExpandEnv(location)
 	}
 
+	return DefaultRootDir()
+}
+
+// DefaultRootDir returns the directory timetrace stores its data in if no
+// store has been configured.
+func DefaultRootDir() string {
 	homeDir, _ := os.UserHomeDir()
 
 	return filepath.Join(homeDir, rootDirName)
 }
 
-func (fs *Fs) WriteReport(filepath string, data []byte) error {
+// isBakFile checks if a given filename is a backup-file
+func isBakFile(filename string) bool {
+	return filepath.Ext(filename) == backupFileExt
+}
+
+// isTempFile checks if the file is a temporary file left behind by an
+// interrupted writeFileAtomic call.
+func isTempFile(filename string) bool {
+	return filepath.Ext(filename) == tempFileExt
+}
+
+// writeReport writes the report data to the given path. If no path is provided,
+// the configured report path is used. If there is no configured report path
+// either, the report is written to the given report directory.
+func writeReport(filepath, configuredPath, reportDir string, data []byte) error {
 	reportPath := filepath
 	// no out flag provided
 	// TODO: looks a little irritating could be re-written
 	if reportPath == "" {
-		reportPath = fs.config.ReportPath
+		reportPath = configuredPath
 		if reportPath == "" {
 			// fileName -> report-<time.unix>
 			fileName := strings.Join([]string{"report", strconv.FormatInt(time.Now().Unix(), 10)}, "-")
-			reportPath = path.Join(fs.ReportDir(), fileName)
+			reportPath = path.Join(reportDir, fileName)
 		}
 	}
 
-	if err := ioutil.WriteFile(reportPath, data, 0644); err != nil {
+	if err := writeFileAtomic(reportPath, data, 0644); err != nil {
 		return err
 	}
 	return nil
 }
+
+// rename moves the temporary file written by writeFileAtomic into place. Tests
+// replace it to simulate a crash before the file has been replaced.
+var rename = os.Rename
+
+// writeFileAtomic writes data to a temporary file in the directory of the given
+// file and renames it to the file once the data has been flushed to disk. This
+// way, the file either contains the old or the new data, but is never left
+// partially written. All files of the store are written using this function.
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	file, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".*"+tempFileExt)
+	if err != nil {
+		return err
+	}
+
+	// Removing the temporary file fails once it has been renamed, which is
+	// fine.
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(file.Name(), perm); err != nil {
+		return err
+	}
+
+	if err := rename(file.Name(), filename); err != nil {
+		return err
+	}
+
+	return syncDir(filepath.Dir(filename))
+}
+
+// syncDir flushes the directory entries of the given directory to disk, so that
+// a renamed file persists a crash. Directories can't be synced on Windows,
+// where renames are persisted by the file system itself.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	if err := d.Sync(); err != nil {
+		d.Close()
+		return err
+	}
+
+	return d.Close()
+}
@@ -0,0 +1,486 @@
+package fs
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dominikbraun/timetrace/config"
+)
+
+// store contains the methods of core.Filesystem that are implemented by both
+// storage backends and covered by the tests.
+type store interface {
+	ProjectKeys() ([]string, error)
+	ProjectBackupKeys() ([]string, error)
+	LoadProject(key string) ([]byte, error)
+	LoadProjectBackup(key string) ([]byte, error)
+	SaveProject(key string, data []byte) error
+	SaveProjectBackup(key string, data []byte) error
+	DeleteProject(key string) error
+	RecordKeys(from, to time.Time) ([]time.Time, error)
+	RecordBackupKeys(from, to time.Time) ([]time.Time, error)
+	LatestRecordKey() (time.Time, error)
+	LoadRecord(start time.Time) ([]byte, error)
+	LoadRecordBackup(start time.Time) ([]byte, error)
+	SaveRecord(start time.Time, data []byte) error
+	SaveRecordBackup(start time.Time, data []byte) error
+	DeleteRecord(start time.Time) error
+	TagKeys() ([]string, error)
+	LoadTag(key string) ([]byte, error)
+	SaveTag(key string, data []byte) error
+	DeleteTag(key string) error
+	EnsureDirectories() error
+	JournalEntries() ([][]byte, error)
+	LatestJournalEntry() ([]byte, error)
+	AppendJournalEntry(data []byte) error
+}
+
+// newStores returns an empty store in a temporary directory for each backend.
+func newStores(t *testing.T) map[string]store {
+	jsonStore := New(&config.Config{Store: t.TempDir()})
+
+	sqliteStore, err := NewSQLite(&config.Config{Store: config.StoreBackendSQLite + ":" + filepath.Join(t.TempDir(), sqliteFileName)})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	t.Cleanup(func() {
+		sqliteStore.db.Close()
+	})
+
+	stores := map[string]store{
+		config.StoreBackendJSON:   jsonStore,
+		config.StoreBackendSQLite: sqliteStore,
+	}
+
+	for name, s := range stores {
+		if err := s.EnsureDirectories(); err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+	}
+
+	return stores
+}
+
+func TestRecordKeys(t *testing.T) {
+	at := func(day, hour, minute, second int) time.Time {
+		return time.Date(2021, 06, day, hour, minute, second, 00, time.Local)
+	}
+
+	starts := []time.Time{
+		at(7, 9, 00, 00),
+		at(7, 23, 59, 00),
+		at(8, 00, 00, 00),
+		at(8, 10, 30, 45),
+	}
+
+	tests := map[string]struct {
+		from     time.Time
+		to       time.Time
+		expected []time.Time
+	}{
+		"all records": {
+			expected: []time.Time{at(7, 9, 00, 00), at(7, 23, 59, 00), at(8, 00, 00, 00), at(8, 10, 30, 00)},
+		},
+		"from is inclusive": {
+			from:     at(8, 00, 00, 00),
+			expected: []time.Time{at(8, 00, 00, 00), at(8, 10, 30, 00)},
+		},
+		"to is exclusive": {
+			to:       at(8, 00, 00, 00),
+			expected: []time.Time{at(7, 9, 00, 00), at(7, 23, 59, 00)},
+		},
+		"single minute": {
+			from:     at(7, 9, 00, 00),
+			to:       at(7, 9, 01, 00),
+			expected: []time.Time{at(7, 9, 00, 00)},
+		},
+		"from within minute": {
+			from:     at(8, 10, 30, 30),
+			expected: []time.Time{},
+		},
+		"to within minute": {
+			from:     at(8, 00, 01, 00),
+			to:       at(8, 10, 30, 30),
+			expected: []time.Time{at(8, 10, 30, 00)},
+		},
+		"from just after minute": {
+			from:     at(8, 00, 00, 00).Add(time.Nanosecond),
+			expected: []time.Time{at(8, 10, 30, 00)},
+		},
+		"to just after minute": {
+			to:       at(8, 00, 00, 00).Add(time.Nanosecond),
+			expected: []time.Time{at(7, 9, 00, 00), at(7, 23, 59, 00), at(8, 00, 00, 00)},
+		},
+		"to just before minute": {
+			to:       at(8, 00, 00, 00).Add(-time.Nanosecond),
+			expected: []time.Time{at(7, 9, 00, 00), at(7, 23, 59, 00)},
+		},
+		"from and to within same minute": {
+			from:     at(8, 10, 30, 10),
+			to:       at(8, 10, 30, 50),
+			expected: []time.Time{},
+		},
+		"empty range": {
+			from:     at(9, 00, 00, 00),
+			expected: []time.Time{},
+		},
+	}
+
+	for backend, s := range newStores(t) {
+		for _, start := range starts {
+			if err := s.SaveRecord(start, []byte("{}")); err != nil {
+				t.Fatalf("%s: unexpected error: %s", backend, err)
+			}
+		}
+
+		// Backups must not be returned as records.
+		if err := s.SaveRecordBackup(at(7, 12, 00, 00), []byte("{}")); err != nil {
+			t.Fatalf("%s: unexpected error: %s", backend, err)
+		}
+
+		for name, tc := range tests {
+			keys, err := s.RecordKeys(tc.from, tc.to)
+			if err != nil {
+				t.Fatalf("%s: %s: unexpected error: %s", backend, name, err)
+			}
+
+			if !equalTimes(keys, tc.expected) {
+				t.Errorf("%s: %s: expected %v, got %v", backend, name, tc.expected, keys)
+			}
+		}
+
+		backupKeys, err := s.RecordBackupKeys(time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", backend, err)
+		}
+
+		if !equalTimes(backupKeys, []time.Time{at(7, 12, 00, 00)}) {
+			t.Errorf("%s: expected only the backup key, got %v", backend, backupKeys)
+		}
+	}
+}
+
+func TestLatestRecordKey(t *testing.T) {
+	for backend, s := range newStores(t) {
+		latest, err := s.LatestRecordKey()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", backend, err)
+		}
+		if !latest.IsZero() {
+			t.Errorf("%s: expected zero time for an empty store, got %s", backend, latest)
+		}
+
+		older := time.Date(2021, 06, 07, 9, 00, 00, 00, time.Local)
+		newer := time.Date(2021, 06, 8, 8, 15, 00, 00, time.Local)
+
+		for _, start := range []time.Time{newer, older} {
+			if err := s.SaveRecord(start, []byte("{}")); err != nil {
+				t.Fatalf("%s: unexpected error: %s", backend, err)
+			}
+		}
+
+		// A younger backup must not be returned as the latest record.
+		if err := s.SaveRecordBackup(newer.AddDate(0, 0, 1), []byte("{}")); err != nil {
+			t.Fatalf("%s: unexpected error: %s", backend, err)
+		}
+
+		latest, err = s.LatestRecordKey()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", backend, err)
+		}
+		if !latest.Equal(newer) {
+			t.Errorf("%s: expected %s, got %s", backend, newer, latest)
+		}
+	}
+}
+
+func TestNotExist(t *testing.T) {
+	start := time.Date(2021, 06, 07, 9, 00, 00, 00, time.Local)
+
+	for backend, s := range newStores(t) {
+		tests := map[string]func() error{
+			"load project": func() error {
+				_, err := s.LoadProject("make-coffee")
+				return err
+			},
+			"load project backup": func() error {
+				_, err := s.LoadProjectBackup("make-coffee")
+				return err
+			},
+			"delete project": func() error {
+				return s.DeleteProject("make-coffee")
+			},
+			"load record": func() error {
+				_, err := s.LoadRecord(start)
+				return err
+			},
+			"load record backup": func() error {
+				_, err := s.LoadRecordBackup(start)
+				return err
+			},
+			"delete record": func() error {
+				return s.DeleteRecord(start)
+			},
+			"load tag": func() error {
+				_, err := s.LoadTag("espresso")
+				return err
+			},
+			"delete tag": func() error {
+				return s.DeleteTag("espresso")
+			},
+		}
+
+		for name, f := range tests {
+			if err := f(); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("%s: %s: expected error %v, got %v", backend, name, os.ErrNotExist, err)
+			}
+		}
+	}
+}
+
+func TestSaveAndDelete(t *testing.T) {
+	start := time.Date(2021, 06, 07, 9, 00, 00, 00, time.Local)
+
+	for backend, s := range newStores(t) {
+		for _, key := range []string{"make-coffee", "clean-kitchen"} {
+			if err := s.SaveProject(key, []byte(key)); err != nil {
+				t.Fatalf("%s: unexpected error: %s", backend, err)
+			}
+		}
+		if err := s.SaveProjectBackup("make-coffee", []byte("backup")); err != nil {
+			t.Fatalf("%s: unexpected error: %s", backend, err)
+		}
+
+		keys, err := s.ProjectKeys()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", backend, err)
+		}
+		if !reflect.DeepEqual(keys, []string{"clean-kitchen", "make-coffee"}) {
+			t.Errorf("%s: unexpected project keys %v", backend, keys)
+		}
+
+		backupKeys, err := s.ProjectBackupKeys()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", backend, err)
+		}
+		if !reflect.DeepEqual(backupKeys, []string{"make-coffee"}) {
+			t.Errorf("%s: unexpected project backup keys %v", backend, backupKeys)
+		}
+
+		if data, err := s.LoadProject("make-coffee"); err != nil || string(data) != "make-coffee" {
+			t.Errorf("%s: expected project data, got %q and %v", backend, data, err)
+		}
+
+		// Deleting a project keeps its backup.
+		if err := s.DeleteProject("make-coffee"); err != nil {
+			t.Fatalf("%s: unexpected error: %s", backend, err)
+		}
+		if _, err := s.LoadProject("make-coffee"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s: expected the project to be deleted, got %v", backend, err)
+		}
+		if data, err := s.LoadProjectBackup("make-coffee"); err != nil || string(data) != "backup" {
+			t.Errorf("%s: expected the project backup to be kept, got %q and %v", backend, data, err)
+		}
+
+		// Saving a record replaces the record with the same start minute.
+		for _, data := range []string{"first", "second"} {
+			if err := s.SaveRecord(start.Add(30*time.Second), []byte(data)); err != nil {
+				t.Fatalf("%s: unexpected error: %s", backend, err)
+			}
+		}
+		if data, err := s.LoadRecord(start); err != nil || string(data) != "second" {
+			t.Errorf("%s: expected the replaced record, got %q and %v", backend, data, err)
+		}
+
+		if err := s.SaveRecordBackup(start, []byte("backup")); err != nil {
+			t.Fatalf("%s: unexpected error: %s", backend, err)
+		}
+		if err := s.DeleteRecord(start); err != nil {
+			t.Fatalf("%s: unexpected error: %s", backend, err)
+		}
+		if _, err := s.LoadRecord(start); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s: expected the record to be deleted, got %v", backend, err)
+		}
+		if data, err := s.LoadRecordBackup(start); err != nil || string(data) != "backup" {
+			t.Errorf("%s: expected the record backup to be kept, got %q and %v", backend, data, err)
+		}
+
+		if err := s.SaveTag("espresso", []byte("espresso")); err != nil {
+			t.Fatalf("%s: unexpected error: %s", backend, err)
+		}
+		if tags, err := s.TagKeys(); err != nil || !reflect.DeepEqual(tags, []string{"espresso"}) {
+			t.Errorf("%s: unexpected tag keys %v and %v", backend, tags, err)
+		}
+		if err := s.DeleteTag("espresso"); err != nil {
+			t.Fatalf("%s: unexpected error: %s", backend, err)
+		}
+		if tags, err := s.TagKeys(); err != nil || len(tags) != 0 {
+			t.Errorf("%s: expected no tags, got %v and %v", backend, tags, err)
+		}
+	}
+}
+
+func TestLatestJournalEntry(t *testing.T) {
+	// The latest entry is longer than the chunks the journal file is read in.
+	entries := []string{`{"id": 1}`, `{"id": 2, "operation": "` + strings.Repeat("x", 2*journalChunkSize) + `"}`}
+
+	for backend, s := range newStores(t) {
+		if latest, err := s.LatestJournalEntry(); err != nil || latest != nil {
+			t.Errorf("%s: expected no entry, got %q and %v", backend, latest, err)
+		}
+
+		for _, entry := range entries {
+			if err := s.AppendJournalEntry([]byte(entry)); err != nil {
+				t.Fatalf("%s: unexpected error: %s", backend, err)
+			}
+
+			if latest, err := s.LatestJournalEntry(); err != nil || string(latest) != entry {
+				t.Errorf("%s: expected the appended entry, got %q and %v", backend, latest, err)
+			}
+		}
+
+		all, err := s.JournalEntries()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", backend, err)
+		}
+		if len(all) != len(entries) {
+			t.Errorf("%s: expected %d entries, got %d", backend, len(entries), len(all))
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "make-coffee.json")
+
+	for _, data := range []string{"first", "second"} {
+		if err := writeFileAtomic(filename, []byte(data), 0600); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if written, err := ioutil.ReadFile(filename); err != nil || string(written) != data {
+			t.Errorf("expected %q, got %q and %v", data, written, err)
+		}
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("expected permissions 0600, got %o", perm)
+	}
+
+	assertDirFiles(t, dir, []string{"make-coffee.json"})
+}
+
+func TestWriteFileAtomicFailure(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "make-coffee.json")
+
+	if err := writeFileAtomic(filename, []byte("original"), 0600); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Simulate a crash right before the temporary file replaces the file.
+	renameErr := errors.New("rename failed")
+	rename = func(string, string) error {
+		return renameErr
+	}
+	defer func() {
+		rename = os.Rename
+	}()
+
+	if err := writeFileAtomic(filename, []byte("changed"), 0600); !errors.Is(err, renameErr) {
+		t.Fatalf("expected error %v, got %v", renameErr, err)
+	}
+
+	if data, err := ioutil.ReadFile(filename); err != nil || string(data) != "original" {
+		t.Errorf("expected the original file to be kept, got %q and %v", data, err)
+	}
+
+	// The temporary file must have been removed.
+	assertDirFiles(t, dir, []string{"make-coffee.json"})
+}
+
+func TestTempFilesIgnored(t *testing.T) {
+	start := time.Date(2021, 06, 07, 9, 00, 00, 00, time.Local)
+
+	store := New(&config.Config{Store: t.TempDir()})
+	if err := store.EnsureDirectories(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := store.SaveProject("make-coffee", []byte("{}")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := store.SaveRecord(start, []byte("{}")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Temporary files left behind by an interrupted write must not be taken
+	// for projects or records.
+	leftovers := []string{
+		filepath.Join(store.projectsDir(), ".clean-kitchen.json.123"+tempFileExt),
+		filepath.Join(store.recordDirFromDate(start), ".10-00.json.123"+tempFileExt),
+	}
+	for _, leftover := range leftovers {
+		if err := ioutil.WriteFile(leftover, []byte("{"), 0600); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	projects, err := store.ProjectKeys()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(projects, []string{"make-coffee"}) {
+		t.Errorf("unexpected project keys %v", projects)
+	}
+
+	records, err := store.RecordKeys(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !equalTimes(records, []time.Time{start}) {
+		t.Errorf("unexpected record keys %v", records)
+	}
+}
+
+func assertDirFiles(t *testing.T, dir string, expected []string) {
+	t.Helper()
+
+	items, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var names []string
+	for _, item := range items {
+		names = append(names, item.Name())
+	}
+
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected files %v in %s, got %v", expected, dir, names)
+	}
+}
+
+func equalTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
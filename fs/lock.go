@@ -0,0 +1,74 @@
+package fs
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const lockFileName = ".lock"
+
+// lockRetryInterval is the interval in which a lock held by another process is
+// tried to be acquired again.
+const lockRetryInterval = 50 * time.Millisecond
+
+// fileLock is an advisory lock on a lock file. The lock is held by the process
+// and released by the operating system if the process exits without releasing
+// it, so a crashed process never leaves a stale lock.
+type fileLock struct {
+	path string
+	file *os.File
+}
+
+// lock acquires the lock, waiting until the given timeout has passed if the
+// lock is held by another process. If the lock can't be acquired in time, an
+// error wrapping os.ErrDeadlineExceeded is returned.
+func (l *fileLock) lock(timeout time.Duration) error {
+	if l.file != nil {
+		return nil
+	}
+
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return err
+		}
+
+		if locked {
+			l.file = file
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			file.Close()
+			return fmt.Errorf("%s: %w", l.path, os.ErrDeadlineExceeded)
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// unlock releases the lock. Releasing a lock that isn't held is a no-op.
+func (l *fileLock) unlock() error {
+	if l.file == nil {
+		return nil
+	}
+
+	file := l.file
+	l.file = nil
+
+	if err := unlockFile(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
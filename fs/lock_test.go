@@ -0,0 +1,56 @@
+package fs
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dominikbraun/timetrace/config"
+)
+
+func TestLock(t *testing.T) {
+	c := &config.Config{Store: t.TempDir()}
+
+	// Two instances for the same store lock the same file just like two
+	// timetrace processes would.
+	first, second := New(c), New(c)
+
+	if err := first.Lock(0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	timeout := 200 * time.Millisecond
+	started := time.Now()
+
+	if err := second.Lock(timeout); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("expected error %v, got %v", os.ErrDeadlineExceeded, err)
+	}
+
+	if waited := time.Since(started); waited < timeout {
+		t.Errorf("expected to wait for %s, gave up after %s", timeout, waited)
+	}
+
+	// The lock becomes available as soon as the first instance releases it.
+	released := make(chan error, 1)
+	go func() {
+		time.Sleep(2 * lockRetryInterval)
+		released <- first.Unlock()
+	}()
+
+	if err := second.Lock(5 * time.Second); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := <-released; err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := second.Unlock(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Releasing a lock that isn't held is a no-op.
+	if err := second.Unlock(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,394 @@
+package fs
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/dominikbraun/timetrace/config"
+
+	// Register the pure-Go SQLite driver, which doesn't require cgo and
+	// therefore works with cross-compiled release builds.
+	_ "modernc.org/sqlite"
+)
+
+const (
+	sqliteDriverName = "sqlite"
+	sqliteFileName   = "timetrace.db"
+)
+
+const (
+	projectsTable       = "projects"
+	projectBackupsTable = "project_backups"
+	recordsTable        = "records"
+	recordBackupsTable  = "record_backups"
+	tagsTable           = "tags"
+	journalTable        = "journal"
+)
+
+// sqliteSchema creates all tables used by the SQLite backend. Projects and tags
+// are keyed by their key, records are keyed by their start time in Unix
+// seconds truncated to minutes, just like the JSON file names.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS projects (key TEXT PRIMARY KEY, data BLOB NOT NULL);
+CREATE TABLE IF NOT EXISTS project_backups (key TEXT PRIMARY KEY, data BLOB NOT NULL);
+CREATE TABLE IF NOT EXISTS records (start INTEGER PRIMARY KEY, data BLOB NOT NULL);
+CREATE TABLE IF NOT EXISTS record_backups (start INTEGER PRIMARY KEY, data BLOB NOT NULL);
+CREATE TABLE IF NOT EXISTS tags (key TEXT PRIMARY KEY, data BLOB NOT NULL);
+CREATE TABLE IF NOT EXISTS journal (id INTEGER PRIMARY KEY AUTOINCREMENT, data BLOB NOT NULL);
+`
+
+// SQLite is a storage backend that stores all projects and records in a single
+// SQLite database file. Other than the JSON file tree, it is able to query
+// records by their start time without reading unrelated records.
+type SQLite struct {
+	config    *config.Config
+	db        *sql.DB
+	storeLock fileLock
+}
+
+// NewSQLite opens the SQLite database file configured in the Store setting.
+// The tables are created when calling EnsureDirectories.
+func NewSQLite(config *config.Config) (*SQLite, error) {
+	s := &SQLite{
+		config: config,
+	}
+
+	db, err := sql.Open(sqliteDriverName, s.databaseFile())
+	if err != nil {
+		return nil, err
+	}
+
+	s.db = db
+
+	return s, nil
+}
+
+// ProjectKeys returns the keys of all non-backup projects sorted alphabetically.
+func (s *SQLite) ProjectKeys() ([]string, error) {
+	return s.keys(projectsTable)
+}
+
+// ProjectBackupKeys returns the keys of all backup projects sorted alphabetically.
+func (s *SQLite) ProjectBackupKeys() ([]string, error) {
+	return s.keys(projectBackupsTable)
+}
+
+// LoadProject returns the stored data of the project with the given key. If
+// the project doesn't exist, an error wrapping os.ErrNotExist is returned.
+func (s *SQLite) LoadProject(key string) ([]byte, error) {
+	return s.load(projectsTable, "key", key)
+}
+
+// LoadProjectBackup returns the stored data of the backup project with the
+// given key.
+func (s *SQLite) LoadProjectBackup(key string) ([]byte, error) {
+	return s.load(projectBackupsTable, "key", key)
+}
+
+// SaveProject stores the data of the project with the given key, replacing any
+// existing project with the same key.
+func (s *SQLite) SaveProject(key string, data []byte) error {
+	return s.save(projectsTable, "key", key, data)
+}
+
+// SaveProjectBackup stores the data of the backup project with the given key.
+func (s *SQLite) SaveProjectBackup(key string, data []byte) error {
+	return s.save(projectBackupsTable, "key", key, data)
+}
+
+// DeleteProject removes the project with the given key. Its backup is kept.
+func (s *SQLite) DeleteProject(key string) error {
+	return s.delete(projectsTable, "key", key)
+}
+
+// RecordKeys returns the start times of all non-backup records that started
+// within the given range, sorted from oldest to newest. The lower boundary is
+// inclusive, the upper boundary is exclusive. A zero time disables the
+// respective boundary.
+func (s *SQLite) RecordKeys(from, to time.Time) ([]time.Time, error) {
+	return s.recordKeys(recordsTable, from, to)
+}
+
+// RecordBackupKeys works like RecordKeys but returns the start times of all
+// backup records.
+func (s *SQLite) RecordBackupKeys(from, to time.Time) ([]time.Time, error) {
+	return s.recordKeys(recordBackupsTable, from, to)
+}
+
+// LatestRecordKey returns the start time of the youngest non-backup record. If
+// there are no records at all, the zero time is returned.
+func (s *SQLite) LatestRecordKey() (time.Time, error) {
+	var start sql.NullInt64
+
+	if err := s.db.QueryRow("SELECT MAX(start) FROM records").Scan(&start); err != nil {
+		return time.Time{}, err
+	}
+
+	if !start.Valid {
+		return time.Time{}, nil
+	}
+
+	return time.Unix(start.Int64, 0), nil
+}
+
+// LoadRecord returns the stored data of the record with the given start time.
+// If the record doesn't exist, an error wrapping os.ErrNotExist is returned.
+func (s *SQLite) LoadRecord(start time.Time) ([]byte, error) {
+	return s.load(recordsTable, "start", recordKey(start))
+}
+
+// LoadRecordBackup returns the stored data of the backup record with the given
+// start time.
+func (s *SQLite) LoadRecordBackup(start time.Time) ([]byte, error) {
+	return s.load(recordBackupsTable, "start", recordKey(start))
+}
+
+// SaveRecord stores the data of the record with the given start time,
+// replacing any existing record with the same start time.
+func (s *SQLite) SaveRecord(start time.Time, data []byte) error {
+	return s.save(recordsTable, "start", recordKey(start), data)
+}
+
+// SaveRecordBackup stores the data of the backup record with the given start
+// time.
+func (s *SQLite) SaveRecordBackup(start time.Time, data []byte) error {
+	return s.save(recordBackupsTable, "start", recordKey(start), data)
+}
+
+// DeleteRecord removes the record with the given start time. Its backup is
+// kept.
+func (s *SQLite) DeleteRecord(start time.Time) error {
+	return s.delete(recordsTable, "start", recordKey(start))
+}
+
+// TagKeys returns the keys of all registered tags sorted alphabetically.
+func (s *SQLite) TagKeys() ([]string, error) {
+	return s.keys(tagsTable)
+}
+
+// LoadTag returns the stored data of the tag with the given key. If the tag
+// doesn't exist, an error wrapping os.ErrNotExist is returned.
+func (s *SQLite) LoadTag(key string) ([]byte, error) {
+	return s.load(tagsTable, "key", key)
+}
+
+// SaveTag stores the data of the tag with the given key, replacing any existing
+// tag with the same key.
+func (s *SQLite) SaveTag(key string, data []byte) error {
+	return s.save(tagsTable, "key", key, data)
+}
+
+// DeleteTag removes the tag with the given key.
+func (s *SQLite) DeleteTag(key string) error {
+	return s.delete(tagsTable, "key", key)
+}
+
+// EnsureDirectories creates the directory containing the database file as well
+// as the report directory and creates all tables. If they already exist,
+// nothing happens.
+func (s *SQLite) EnsureDirectories() error {
+	dirs := []string{
+		filepath.Dir(s.databaseFile()),
+		s.ReportDir(),
+	}
+
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return err
+		}
+	}
+
+	_, err := s.db.Exec(sqliteSchema)
+
+	return err
+}
+
+// JournalEntries returns the data of all journal entries in the order they have
+// been appended.
+func (s *SQLite) JournalEntries() ([][]byte, error) {
+	rows, err := s.db.Query(fmt.Sprintf("SELECT data FROM %s ORDER BY id", journalTable))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries [][]byte
+
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		entries = append(entries, data)
+	}
+
+	return entries, rows.Err()
+}
+
+// LatestJournalEntry returns the data of the latest journal entry, or nil if
+// the journal is empty.
+func (s *SQLite) LatestJournalEntry() ([]byte, error) {
+	var data []byte
+
+	err := s.db.QueryRow(fmt.Sprintf("SELECT data FROM %s ORDER BY id DESC LIMIT 1", journalTable)).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return data, err
+}
+
+// AppendJournalEntry appends the data of a journal entry to the journal.
+func (s *SQLite) AppendJournalEntry(data []byte) error {
+	_, err := s.db.Exec(fmt.Sprintf("INSERT INTO %s (data) VALUES (?)", journalTable), data)
+	return err
+}
+
+// Lock acquires an advisory lock on a lock file next to the database file. See
+// Fs.Lock for details. SQLite itself only locks the database per statement,
+// but timetrace reads and writes records using separate statements.
+func (s *SQLite) Lock(timeout time.Duration) error {
+	s.storeLock.path = s.databaseFile() + lockFileName
+	return s.storeLock.lock(timeout)
+}
+
+// Unlock releases the lock acquired by Lock.
+func (s *SQLite) Unlock() error {
+	return s.storeLock.unlock()
+}
+
+// ReportDir returns the directory reports are written to by default. It is
+// located next to the database file.
+func (s *SQLite) ReportDir() string {
+	return path.Join(filepath.Dir(s.databaseFile()), reportDirName)
+}
+
+func (s *SQLite) WriteReport(filepath string, data []byte) error {
+	return writeReport(filepath, s.config.ReportPath, s.ReportDir(), data)
+}
+
+func (s *SQLite) keys(table string) ([]string, error) {
+	rows, err := s.db.Query(fmt.Sprintf("SELECT key FROM %s ORDER BY key", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (s *SQLite) recordKeys(table string, from, to time.Time) ([]time.Time, error) {
+	query := fmt.Sprintf("SELECT start FROM %s WHERE 1 = 1", table)
+	var args []interface{}
+
+	// The query only narrows down the keys to the minutes of the boundaries.
+	// The keys are then compared to the boundaries exactly like Fs.RecordKeys
+	// does, so that both backends return the same keys for any range.
+	if !from.IsZero() {
+		query += " AND start >= ?"
+		args = append(args, recordKey(from))
+	}
+
+	if !to.IsZero() {
+		query += " AND start <= ?"
+		args = append(args, recordKey(to))
+	}
+
+	rows, err := s.db.Query(query+" ORDER BY start", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]time.Time, 0)
+
+	for rows.Next() {
+		var start int64
+		if err := rows.Scan(&start); err != nil {
+			return nil, err
+		}
+
+		key := time.Unix(start, 0)
+
+		if !from.IsZero() && key.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !key.Before(to) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (s *SQLite) load(table, column string, key interface{}) ([]byte, error) {
+	var data []byte
+
+	query := fmt.Sprintf("SELECT data FROM %s WHERE %s = ?", table, column)
+
+	if err := s.db.QueryRow(query, key).Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s %v: %w", table, key, os.ErrNotExist)
+		}
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (s *SQLite) save(table, column string, key interface{}, data []byte) error {
+	query := fmt.Sprintf("INSERT OR REPLACE INTO %s (%s, data) VALUES (?, ?)", table, column)
+	_, err := s.db.Exec(query, key, data)
+
+	return err
+}
+
+func (s *SQLite) delete(table, column string, key interface{}) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", table, column)
+
+	result, err := s.db.Exec(query, key)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s %v: %w", table, key, os.ErrNotExist)
+	}
+
+	return nil
+}
+
+func (s *SQLite) databaseFile() string {
+	if _, location := s.config.StoreLocation(); location != "" {
+		return os.ExpandEnv(location)
+	}
+
+	return filepath.Join(DefaultRootDir(), sqliteFileName)
+}
+
+// recordKey returns the key of a record with the given start time. Just like
+// the JSON file names, the key has minute precision.
+func recordKey(start time.Time) int64 {
+	return start.Truncate(time.Minute).Unix()
+}
@@ -10,4 +10,6 @@ require (
 	github.com/rivo/uniseg v0.4.4 // indirect
 	github.com/spf13/cobra v1.7.0
 	github.com/spf13/viper v1.16.0
+	golang.org/x/sys v0.8.0
+	modernc.org/sqlite v1.18.2
 )
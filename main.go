@@ -18,7 +18,16 @@ func main() {
 		out.Warn("%s", err.Error())
 	}
 
-	filesystem := fs.New(c)
+	if _, err := c.Location(); err != nil {
+		out.Warn("invalid time zone, using the local time zone: %s", err.Error())
+	}
+
+	filesystem, err := newFilesystem(c)
+	if err != nil {
+		out.Err("%s", err.Error())
+		os.Exit(1)
+	}
+
 	timetrace := core.New(c, filesystem)
 
 	if err := cli.RootCommand(timetrace, version).Execute(); err != nil {
@@ -26,3 +35,12 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// newFilesystem creates the storage backend selected by the Store setting.
+func newFilesystem(c *config.Config) (core.Filesystem, error) {
+	if backend, _ := c.StoreLocation(); backend == config.StoreBackendSQLite {
+		return fs.NewSQLite(c)
+	}
+
+	return fs.New(c), nil
+}